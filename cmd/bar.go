@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/ui"
+)
+
+func runBar(cfg *config.Config, database *db.DB, out *os.File, args []string) {
+	fs := flag.NewFlagSet("bar", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: keiko bar --format waybar|i3bar|polybar")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", ui.BarWaybar, "Bar protocol: waybar, i3bar or polybar")
+	hotkeys := fs.Bool("hotkeys", false, "Grab the global hotkeys, when no other keiko does")
+	fs.Parse(args)
+
+	sink, err := ui.NewBarSink(*format, out)
+	if err != nil || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	runStatusBar(cfg, database, sink, *hotkeys, func(statusBar *ui.StatusBar) {
+		if *format != ui.BarI3bar {
+			return
+		}
+		go func() {
+			if err := ui.ReadI3Clicks(os.Stdin, statusBar.Click); err != nil {
+				log.Printf("i3bar clicks stopped: %v", err)
+			}
+		}()
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/LealKevin/keiko/internal/ctl"
+)
+
+func runCtl(args []string) {
+	if len(args) != 1 || !slices.Contains(ctl.Commands, args[0]) {
+		fmt.Printf("Usage: keiko ctl %s\n", strings.Join(ctl.Commands, "|"))
+		os.Exit(2)
+	}
+
+	reply, err := ctl.Send(ctl.SocketPath(), args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(reply)
+}
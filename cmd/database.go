@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LealKevin/keiko/internal/db"
+)
+
+func runDB(database *db.DB, dbFilePath string, args []string) {
+	if len(args) == 0 || args[0] != "status" {
+		fmt.Println("Usage: keiko db status")
+		os.Exit(2)
+	}
+
+	version, err := database.SchemaVersion()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	migrations, err := database.Migrations()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Database:", dbFilePath)
+	fmt.Printf("Schema version: %d (latest %d)\n", version, db.LatestSchemaVersion())
+	fmt.Println()
+
+	fmt.Println("Migrations")
+	for _, m := range migrations {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  %3d  %-35s %s\n", m.Version, m.Name, applied)
+	}
+
+	backups, err := database.Backups()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(backups) > 0 {
+		fmt.Println()
+		fmt.Println("Backups")
+		for _, b := range backups {
+			fmt.Println("  " + b)
+		}
+	}
+}
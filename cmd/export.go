@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/service"
+)
+
+func runExport(cfg *config.Config, database *db.DB, args []string) {
+	if len(args) == 0 || args[0] != "anki" {
+		fmt.Println("Usage: keiko export anki [options]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("export anki", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: keiko export anki [options]")
+		fs.PrintDefaults()
+	}
+
+	_, defaultNoteType := cfg.UserConfig.AnkiAddTarget()
+	levelList := fs.String("levels", "5,4,3,2,1", "JLPT levels to export, comma separated")
+	deck := fs.String("deck", "JLPT", "Anki deck to export into (created if missing)")
+	noteType := fs.String("note-type", defaultNoteType, "Anki note type of the exported notes")
+	fs.Parse(args[1:])
+
+	levels, err := parseLevels(*levelList)
+	if err != nil || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client := cfg.UserConfig.AnkiClient()
+	if err := client.Ping(); err != nil {
+		fmt.Println("Anki is not reachable, open Anki Desktop with AnkiConnect:", err)
+		os.Exit(1)
+	}
+
+	result, err := service.NewExportService(database).ExportToAnki(client, *deck, *noteType, levels)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Added %d notes to %q, skipped %d already there\n", result.Added, *deck, result.Skipped)
+	if result.Duplicates > 0 {
+		fmt.Printf("Left out %d words sharing their first field with another word of the export\n", result.Duplicates)
+	}
+}
+
+func parseLevels(list string) ([]int, error) {
+	var levels []int
+	for _, s := range strings.Split(list, ",") {
+		level, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || level < 1 || level > 5 {
+			return nil, fmt.Errorf("invalid JLPT level %q", s)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+)
+
+func runImport(database *db.DB, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: keiko import --deck <name> [options] <file.csv|file.tsv|file.json>")
+		fs.PrintDefaults()
+	}
+
+	defaults := data.DefaultColumnMapping()
+	deck := fs.String("deck", "", "Deck to import into (created if missing)")
+	format := fs.String("format", "", "File format: csv, tsv or json (default: from file extension)")
+	wordCol := fs.String("word", defaults.Word, "Column with the word (header name or 1-based index)")
+	readingCol := fs.String("reading", defaults.Reading, "Column with the reading, empty to skip")
+	meaningCol := fs.String("meaning", defaults.Meaning, "Column with the meaning, empty to skip")
+	noHeader := fs.Bool("no-header", false, "CSV/TSV file has no header row (map columns by index)")
+	fs.Parse(args)
+
+	if *deck == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = data.DeckFormat(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	mapping := data.ColumnMapping{Word: *wordCol, Reading: *readingCol, Meaning: *meaningCol}
+	words, err := data.ParseDeck(file, *format, mapping, !*noHeader)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	added, err := database.ImportDeck(*deck, words)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d new words into %q (%d in file)\n", added, *deck, len(words))
+	fmt.Println("Select the deck in settings (F2) to study it.")
+}
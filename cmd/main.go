@@ -3,19 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/LealKevin/keiko/internal/anki"
 	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/ctl"
 	"github.com/LealKevin/keiko/internal/data"
 	"github.com/LealKevin/keiko/internal/db"
 	"github.com/LealKevin/keiko/internal/news"
 	"github.com/LealKevin/keiko/internal/service"
+	"github.com/LealKevin/keiko/internal/srs"
 	"github.com/LealKevin/keiko/internal/tui"
 	"github.com/LealKevin/keiko/internal/ui"
 	tea "github.com/charmbracelet/bubbletea"
@@ -23,13 +26,36 @@ import (
 	hook "github.com/robotn/gohook"
 )
 
-const (
-	KeyF2 = 0x003C // Settings
-	KeyF3 = 0x003D // Toggle mode
-	KeyF4 = 0x003E // Reveal answer
-	KeyF5 = 0x003F // Again (ease 1)
-	KeyF6 = 0x0040 // Good (ease 3)
-)
+// keycodes maps the key names used in the keys config to gohook keycodes.
+var keycodes = map[string]uint16{
+	"F1":  0x003B,
+	"F2":  0x003C,
+	"F3":  0x003D,
+	"F4":  0x003E,
+	"F5":  0x003F,
+	"F6":  0x0040,
+	"F7":  0x0041,
+	"F8":  0x0042,
+	"F9":  0x0043,
+	"F10": 0x0044,
+	"F11": 0x0057,
+	"F12": 0x0058,
+}
+
+// isKey reports whether the event is the key named in the config.
+func isKey(ev hook.Event, name string) bool {
+	code, ok := keycodes[strings.ToUpper(name)]
+	return ok && ev.Keycode == code
+}
+
+// checkKeys warns about key names that cannot be bound.
+func checkKeys(keys config.KeyBindings) {
+	for _, name := range []string{keys.Settings, keys.ToggleMode, keys.Reveal, keys.Again, keys.Hard, keys.Good, keys.Easy} {
+		if _, ok := keycodes[strings.ToUpper(name)]; !ok {
+			log.Printf("Unknown key %q in config, use F1 to F12", name)
+		}
+	}
+}
 
 var (
 	tuiMode          = flag.Bool("tui", false, "Run in TUI mode")
@@ -37,6 +63,20 @@ var (
 )
 
 func main() {
+	flag.Parse()
+
+	// ctl talks to the running daemon and needs no config or database.
+	if flag.Arg(0) == "ctl" {
+		runCtl(flag.Args()[1:])
+		return
+	}
+
+	// The bar protocols own stdout, so messages go to stderr.
+	barOut := os.Stdout
+	if flag.Arg(0) == "bar" {
+		os.Stdout = os.Stderr
+	}
+
 	configDir, _ := os.UserConfigDir()
 	appDir := filepath.Join(configDir, "keiko")
 
@@ -73,8 +113,10 @@ func main() {
 		fmt.Println(err)
 		panic(err)
 	}
+	if backup := database.BackupPath(); backup != "" {
+		fmt.Fprintln(os.Stderr, "Database upgraded, backup saved to", backup)
+	}
 
-	flag.Parse()
 	if *tuiMode {
 		runTui(c, database)
 		return
@@ -88,7 +130,7 @@ func main() {
 	}
 
 	if count == 0 {
-		words, err := data.FetchWords()
+		words, err := data.BundledWords()
 		if err != nil {
 			panic(err)
 		}
@@ -100,24 +142,97 @@ func main() {
 		}
 	}
 
+	kanjiCount, err := database.GetKanjiCount()
+	if err != nil {
+		panic(err)
+	}
+
+	// Newer releases ship more kanji; the missing ones are added on start.
+	kanji, err := data.BundledKanji()
+	if err != nil {
+		panic(err)
+	}
+
+	if kanjiCount < len(kanji) {
+		if _, err := database.SeedKanji(kanji); err != nil {
+			fmt.Println(err)
+			panic(err)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "stats":
+		runStats(service.New(database))
+		return
+	case "vocab":
+		runVocab(database, flag.Args()[1:])
+		return
+	case "import":
+		runImport(database, flag.Args()[1:])
+		return
+	case "export":
+		runExport(c, database, flag.Args()[1:])
+		return
+	case "db":
+		runDB(database, dbFilePath, flag.Args()[1:])
+		return
+	case "bar":
+		runBar(c, database, barOut, flag.Args()[1:])
+		return
+	}
+
+	displayFile := c.UserConfig.DisplayFile
+	if displayFile == "" {
+		displayFile = filepath.Join(appDir, "status.txt")
+	}
+	sink, err := ui.NewSink(c.UserConfig.Display, displayFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	runStatusBar(c, database, sink, true, nil)
+}
+
+// runStatusBar shows cards on the sink and reviews them until interrupted,
+// with the global hotkeys when hotkeys is set. start is called once the
+// status bar is set up.
+func runStatusBar(c *config.Config, database *db.DB, sink ui.Sink, hotkeys bool, start func(*ui.StatusBar)) {
+	newsClient := news.NewClient(c.UserConfig.NewsServerURL)
+	sentences := service.NewSentenceService(database, newsClient)
+	ankiCache := service.NewAnkiCacheService(database)
 	service := service.New(database)
-	statusBar := ui.NewStatusBar(service, c)
+	statusBar := ui.NewStatusBar(service, sentences, ankiCache, c, sink)
 	statusBar.Init()
 	statusBar.Refresh()
 
-	fmt.Println("Setup complete!")
+	server, err := ctl.Listen(ctl.SocketPath(), statusBar)
+	// Messages go to the log, as the stdout display owns stdout.
+	if err != nil {
+		log.Printf("Control socket disabled: %v", err)
+	} else {
+		go server.Serve()
+	}
+
+	log.Println("Setup complete!")
+	if start != nil {
+		start(statusBar)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	c.Watch()
 
-	go keyboardListener(statusBar, c)
+	if hotkeys {
+		checkKeys(c.UserConfig.Keys)
+		go keyboardListener(statusBar, c)
+	}
 
-	// Background polling for Anki due count refresh
+	// Background polling for Anki due count refresh and syncs
 	go func() {
 		for range time.Tick(anki.RefreshInterval) {
 			statusBar.RefreshAnkiDueCount()
+			statusBar.SyncAnkiIfDue()
 		}
 	}()
 
@@ -125,7 +240,8 @@ func main() {
 	for {
 		select {
 		case <-ticker.C:
-			if statusBar.Mode() == ui.VocabMode {
+			// Cards wait for a grade, so only poll when nothing was due.
+			if statusBar.Mode() != ui.AnkiMode && statusBar.VocabState() == ui.StateDone {
 				statusBar.Refresh()
 			}
 			ticker.Reset(time.Second * time.Duration(c.UserConfig.LoopInterval))
@@ -133,7 +249,10 @@ func main() {
 			ticker.Reset(time.Second * time.Duration(c.UserConfig.LoopInterval))
 			statusBar.OnConfigChange()
 		case <-sigChan:
-			fmt.Println("Exiting...")
+			log.Println("Exiting...")
+			if server != nil {
+				server.Close()
+			}
 			statusBar.Close()
 			os.Exit(0)
 		}
@@ -149,25 +268,37 @@ func runTui(cfg *config.Config, database *db.DB) {
 	}
 }
 
-func openTui() {
-	path, err := os.Executable()
+func runVocab(database *db.DB, args []string) {
+	if len(args) == 0 || args[0] != "update" {
+		fmt.Println("Usage: keiko vocab update")
+		os.Exit(2)
+	}
+
+	fmt.Println("Fetching the latest JLPT vocabulary...")
+	words, err := data.FetchWords()
 	if err != nil {
-		fmt.Println("Error getting executable path:", err)
-		return
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	if err := exec.Command("tmux", "display-popup", "-w", "80%", "-h", "80%", "-E", path, "--tui").Run(); err != nil {
-		fmt.Printf("tmux popup failed: %v\n", err)
+
+	added, err := database.UpdateVocab(words)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+	fmt.Printf("Added %d new words (%d fetched)\n", added, len(words))
 }
 
-func openDeckSelector() {
+// openTui opens the settings in a popup of the display, with the extra
+// flags.
+func openTui(statusBar *ui.StatusBar, flags ...string) {
 	path, err := os.Executable()
 	if err != nil {
-		fmt.Println("Error getting executable path:", err)
+		log.Printf("Error getting executable path: %v", err)
 		return
 	}
-	if err := exec.Command("tmux", "display-popup", "-w", "80%", "-h", "80%", "-E", path, "--tui", "--deck-selector").Run(); err != nil {
-		fmt.Printf("tmux popup failed: %v\n", err)
+	if err := statusBar.OpenPopup(append([]string{path, "--tui"}, flags...)...); err != nil {
+		log.Printf("Settings popup failed: %v", err)
 	}
 }
 
@@ -180,21 +311,26 @@ func keyboardListener(statusBar *ui.StatusBar, cfg *config.Config) {
 			continue
 		}
 
-		switch ev.Keycode {
-		case KeyF2:
-			openTui()
-		case KeyF3:
+		keys := cfg.UserConfig.Keys
+		switch {
+		case isKey(ev, keys.Settings):
+			openTui(statusBar)
+		case isKey(ev, keys.ToggleMode):
 			if statusBar.NeedsDeckSelector() {
-				openDeckSelector()
+				openTui(statusBar, "--deck-selector")
 			} else {
 				statusBar.ToggleMode()
 			}
-		case KeyF4:
+		case isKey(ev, keys.Reveal):
 			statusBar.RevealAnswer()
-		case KeyF5:
-			statusBar.AnswerCard(1) // Again
-		case KeyF6:
-			statusBar.AnswerCard(3) // Good
+		case isKey(ev, keys.Again):
+			statusBar.AnswerCard(int(srs.Again))
+		case isKey(ev, keys.Hard):
+			statusBar.AnswerCard(int(srs.Hard))
+		case isKey(ev, keys.Good):
+			statusBar.AnswerCard(int(srs.Good))
+		case isKey(ev, keys.Easy):
+			statusBar.AnswerCard(int(srs.Easy))
 		}
 	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/LealKevin/keiko/internal/service"
+)
+
+func runStats(svc service.VocabService) {
+	stats, err := svc.GetStats()
+	if err != nil {
+		fmt.Println("Error loading stats:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Reviews (last 7 days)")
+	for _, d := range stats.Daily {
+		fmt.Printf("  %s  %4d  %s\n", d.Day, d.Reviews, strings.Repeat("▇", min(d.Reviews/5, 40)))
+	}
+	fmt.Println()
+
+	if stats.Reviewed > 0 {
+		fmt.Printf("Retention (30 days): %.1f%% of %d reviews\n", stats.Retention*100, stats.Reviewed)
+	} else {
+		fmt.Println("Retention (30 days): no reviews yet")
+	}
+	fmt.Printf("Current streak: %d day(s)\n", stats.Streak)
+	fmt.Println()
+
+	fmt.Println("JLPT progress (seen / total, learned, due)")
+	for _, l := range stats.Levels {
+		percent := 0.0
+		if l.Total > 0 {
+			percent = float64(l.Seen) / float64(l.Total) * 100
+		}
+		fmt.Printf("  N%d  %5d / %-5d %5.1f%%  %5d learned  %4d due\n", l.Level, l.Seen, l.Total, percent, l.Learned, l.Due)
+	}
+}
@@ -3,21 +3,56 @@ package anki
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
 
 const (
-	BaseURL         = "http://localhost:8765"
+	DefaultURL      = "http://localhost:8765"
 	RefreshInterval = 30 * time.Second
+	// CacheInterval is how often the due cards are cached for reviewing
+	// while Anki is closed.
+	CacheInterval = 5 * time.Minute
 )
 
+// ErrorUnauthorized means AnkiConnect answered but rejected the API key.
+var ErrorUnauthorized = errors.New("anki: unauthorized, check anki_api_key")
+
+// ErrorForbidden means AnkiConnect does not trust the origin of the request,
+// which webCorsOriginList in its config decides.
+var ErrorForbidden = errors.New("anki: forbidden, check webCorsOriginList in the AnkiConnect config")
+
 type Client struct {
 	http *http.Client
+	url  string
+	key  string
+
+	mappings fieldMappings
+}
+
+// fieldMappings are the user's field roles, keyed by note type name.
+type fieldMappings map[string]FieldMapping
+
+// FieldMapping names the note fields shown as question, reading and answer.
+// An empty reading means the note type has none. Sentence is only filled when
+// adding notes.
+type FieldMapping struct {
+	Question string
+	Reading  string
+	Answer   string
+	Sentence string
+}
+
+// NoteType is an Anki note type with its fields in order.
+type NoteType struct {
+	Name   string
+	Fields []string
 }
 
 type DeckInfo struct {
@@ -26,20 +61,43 @@ type DeckInfo struct {
 }
 
 type CardInfo struct {
-	CardID   int64
-	DeckName string
-	Question string // Word/front
-	Reading  string // Furigana/reading
-	Answer   string // Meaning/back
+	CardID    int64
+	DeckName  string
+	ModelName string // Note type
+	Question  string // Word/front
+	Reading   string // Furigana/reading
+	Answer    string // Meaning/back
+	Mod       int64  // Last modification, changes when the card is reviewed
 }
 
-func NewClient() *Client {
+// Answer is a grade given to a card.
+type Answer struct {
+	CardID int64 `json:"cardId"`
+	Ease   int   `json:"ease"`
+}
+
+// NewClient talks to AnkiConnect at url, or DefaultURL when empty. The key is
+// only sent when AnkiConnect is configured with an apiKey.
+func NewClient(url, key string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
 	return &Client{
 		http: &http.Client{Timeout: 5 * time.Second},
+		url:  url,
+		key:  key,
 	}
 }
 
 type ankiRequest struct {
+	Action  string      `json:"action"`
+	Version int         `json:"version"`
+	Key     string      `json:"key,omitempty"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// ankiAction is one action of a multi request.
+type ankiAction struct {
 	Action  string      `json:"action"`
 	Version int         `json:"version"`
 	Params  interface{} `json:"params,omitempty"`
@@ -54,6 +112,7 @@ func (c *Client) call(action string, params interface{}) (json.RawMessage, error
 	req := ankiRequest{
 		Action:  action,
 		Version: 6,
+		Key:     c.key,
 		Params:  params,
 	}
 
@@ -62,27 +121,145 @@ func (c *Client) call(action string, params interface{}) (json.RawMessage, error
 		return nil, err
 	}
 
-	resp, err := c.http.Post(BaseURL, "application/json", bytes.NewReader(body))
+	resp, err := c.http.Post(c.url, "application/json", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	// AnkiConnect answers 403 to requests from origins it does not trust.
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrorForbidden
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrorUnauthorized
+	}
+
 	var ar ankiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ar); err != nil {
 		return nil, err
 	}
 
 	if ar.Error != nil {
+		if strings.Contains(strings.ToLower(*ar.Error), "api key") {
+			return nil, ErrorUnauthorized
+		}
 		return nil, fmt.Errorf("anki error: %s", *ar.Error)
 	}
 
 	return ar.Result, nil
 }
 
-func (c *Client) IsConnected() bool {
+// multi runs the actions in a single request and returns their results in
+// order. It fails if any of them failed.
+func (c *Client) multi(actions ...ankiAction) ([]json.RawMessage, error) {
+	for i := range actions {
+		actions[i].Version = 6
+	}
+
+	result, err := c.call("multi", map[string]interface{}{
+		"actions": actions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []ankiResponse
+	if err := json.Unmarshal(result, &responses); err != nil {
+		return nil, err
+	}
+	if len(responses) != len(actions) {
+		return nil, fmt.Errorf("anki error: %d results for %d actions", len(responses), len(actions))
+	}
+
+	results := make([]json.RawMessage, len(responses))
+	for i, r := range responses {
+		if r.Error != nil {
+			return nil, fmt.Errorf("anki error: %s: %s", actions[i].Action, *r.Error)
+		}
+		results[i] = r.Result
+	}
+	return results, nil
+}
+
+// Ping checks that AnkiConnect is reachable and accepts the API key. It
+// returns ErrorUnauthorized when the key is missing or wrong.
+func (c *Client) Ping() error {
 	_, err := c.call("version", nil)
-	return err == nil
+	return err
+}
+
+func (c *Client) IsConnected() bool {
+	return c.Ping() == nil
+}
+
+// SetFieldMapping makes cards of the note type use the given fields instead
+// of guessing them from common field names.
+func (c *Client) SetFieldMapping(noteType string, mapping FieldMapping) {
+	if c.mappings == nil {
+		c.mappings = make(fieldMappings)
+	}
+	c.mappings[noteType] = mapping
+}
+
+// GetDeckNoteTypes lists the note types used by notes in the deck.
+func (c *Client) GetDeckNoteTypes(deck string) ([]NoteType, error) {
+	result, err := c.call("modelNames", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := json.Unmarshal(result, &names); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	findNotes := make([]ankiAction, len(names))
+	for i, name := range names {
+		findNotes[i] = ankiAction{Action: "findNotes", Params: map[string]interface{}{
+			"query": fmt.Sprintf("deck:\"%s\" note:\"%s\"", deck, name),
+		}}
+	}
+	results, err := c.multi(findNotes...)
+	if err != nil {
+		return nil, err
+	}
+
+	var used []string
+	var fieldNames []ankiAction
+	for i, name := range names {
+		var notes []int64
+		if err := json.Unmarshal(results[i], &notes); err != nil {
+			return nil, err
+		}
+		if len(notes) == 0 {
+			continue
+		}
+		used = append(used, name)
+		fieldNames = append(fieldNames, ankiAction{Action: "modelFieldNames", Params: map[string]interface{}{
+			"modelName": name,
+		}})
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+
+	results, err = c.multi(fieldNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	noteTypes := make([]NoteType, len(used))
+	for i, name := range used {
+		var fields []string
+		if err := json.Unmarshal(results[i], &fields); err != nil {
+			return nil, err
+		}
+		noteTypes[i] = NoteType{Name: name, Fields: fields}
+	}
+
+	return noteTypes, nil
 }
 
 func (c *Client) GetDecksWithStats() ([]DeckInfo, error) {
@@ -96,132 +273,243 @@ func (c *Client) GetDecksWithStats() ([]DeckInfo, error) {
 		return nil, err
 	}
 
-	var decks []DeckInfo
+	names := make([]string, 0, len(deckMap))
 	for name := range deckMap {
-		dueCount, err := c.GetDueCount(name)
-		if err != nil {
-			dueCount = 0
-		}
-		decks = append(decks, DeckInfo{Name: name, DueCount: dueCount})
+		names = append(names, name)
+	}
+
+	// Stats are best effort, decks are listed with 0 due without them.
+	stats, err := c.getDeckStats(names)
+	if err != nil {
+		stats = nil
+	}
+
+	decks := make([]DeckInfo, len(names))
+	for i, name := range names {
+		s := stats[name]
+		decks[i] = DeckInfo{Name: name, DueCount: s.NewCount + s.LearnCount + s.ReviewCount}
 	}
 
 	return decks, nil
 }
 
-func (c *Client) GetDueCount(deck string) (int, error) {
-	result, err := c.call("getDeckStats", map[string]interface{}{
-		"decks": []string{deck},
-	})
+// GetDueCount returns the due cards of the source: learning and review cards,
+// plus the new cards each deck still shows today. It counts the same cards
+// GetDueCards returns, so the counter agrees with the queue.
+func (c *Client) GetDueCount(src Source) (int, error) {
+	cards, err := c.GetDueCards(src)
 	if err != nil {
 		return 0, err
 	}
+	return len(cards), nil
+}
 
-	var stats map[string]struct {
-		NewCount    int `json:"new_count"`
-		LearnCount  int `json:"learn_count"`
-		ReviewCount int `json:"review_count"`
+type deckStats struct {
+	Name        string `json:"name"`
+	NewCount    int    `json:"new_count"`
+	LearnCount  int    `json:"learn_count"`
+	ReviewCount int    `json:"review_count"`
+}
+
+// getDeckStats returns today's counts by deck name.
+func (c *Client) getDeckStats(decks []string) (map[string]deckStats, error) {
+	result, err := c.call("getDeckStats", map[string]interface{}{
+		"decks": decks,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(result, &stats); err != nil {
-		return 0, err
+
+	return parseDeckStats(result)
+}
+
+func parseDeckStats(result json.RawMessage) (map[string]deckStats, error) {
+	var byID map[string]deckStats
+	if err := json.Unmarshal(result, &byID); err != nil {
+		return nil, err
 	}
 
-	for _, s := range stats {
-		return s.NewCount + s.LearnCount + s.ReviewCount, nil
+	stats := make(map[string]deckStats, len(byID))
+	for _, s := range byID {
+		stats[s.Name] = s
 	}
-	return 0, nil
+	return stats, nil
 }
 
-func (c *Client) GetDueCards(deck string) ([]int64, error) {
-	statsResult, err := c.call("getDeckStats", map[string]interface{}{
-		"decks": []string{deck},
-	})
+// GetDueCards returns the due cards of the source, followed by its new cards
+// up to what each deck still shows today. It takes at most two requests.
+func (c *Client) GetDueCards(src Source) ([]int64, error) {
+	search := src.Search()
+
+	results, err := c.multi(
+		ankiAction{Action: "findCards", Params: map[string]interface{}{"query": search + " is:due"}},
+		ankiAction{Action: "findCards", Params: map[string]interface{}{"query": search + " is:new"}},
+		ankiAction{Action: "deckNames"},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	var stats map[string]struct {
-		NewCount int `json:"new_count"`
+	var dueCards, newCards []int64
+	var deckNames []string
+	if err := json.Unmarshal(results[0], &dueCards); err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(statsResult, &stats); err != nil {
+	if err := json.Unmarshal(results[1], &newCards); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(results[2], &deckNames); err != nil {
 		return nil, err
 	}
 
-	var newLimit int
-	for _, s := range stats {
-		newLimit = s.NewCount
-		break
+	if len(newCards) == 0 {
+		return dueCards, nil
 	}
 
-	dueResult, err := c.call("findCards", map[string]interface{}{
-		"query": fmt.Sprintf("deck:\"%s\" is:due", deck),
-	})
+	limited, err := c.limitNewCards(newCards, deckNames, src.Decks)
 	if err != nil {
 		return nil, err
 	}
+	return append(dueCards, limited...), nil
+}
 
-	var dueCards []int64
-	if err := json.Unmarshal(dueResult, &dueCards); err != nil {
+// limitNewCards keeps, for each deck, as many new cards as its daily limit
+// still allows, in the order they were found. A configured deck with subdecks
+// also caps them all together, as Anki does when studying it.
+func (c *Client) limitNewCards(cards []int64, deckNames []string, configured []string) ([]int64, error) {
+	results, err := c.multi(
+		ankiAction{Action: "getDecks", Params: map[string]interface{}{"cards": cards}},
+		ankiAction{Action: "getDeckStats", Params: map[string]interface{}{"decks": deckNames}},
+	)
+	if err != nil {
 		return nil, err
 	}
 
-	if newLimit > 0 {
-		newResult, err := c.call("findCards", map[string]interface{}{
-			"query": fmt.Sprintf("deck:\"%s\" is:new", deck),
-		})
-		if err == nil {
-			var newCards []int64
-			if err := json.Unmarshal(newResult, &newCards); err == nil {
-				if len(newCards) > newLimit {
-					newCards = newCards[:newLimit]
-				}
-				dueCards = append(dueCards, newCards...)
-			}
+	var byDeck map[string][]int64
+	if err := json.Unmarshal(results[0], &byDeck); err != nil {
+		return nil, err
+	}
+	stats, err := parseDeckStats(results[1])
+	if err != nil {
+		return nil, err
+	}
+
+	deckOf := make(map[int64]string, len(cards))
+	for deck, ids := range byDeck {
+		for _, id := range ids {
+			deckOf[id] = deck
+		}
+	}
+
+	left := make(map[string]int, len(stats))
+	for name, s := range stats {
+		left[name] = s.NewCount
+	}
+
+	var limited []int64
+	for _, id := range cards {
+		deck := deckOf[id]
+		parent := configuredParent(deck, configured)
+		if left[deck] <= 0 || (parent != "" && left[parent] <= 0) {
+			continue
+		}
+		limited = append(limited, id)
+		left[deck]--
+		if parent != "" && parent != deck {
+			left[parent]--
 		}
 	}
+	return limited, nil
+}
 
-	return dueCards, nil
+// configuredParent returns the configured deck that deck is in, if any.
+func configuredParent(deck string, configured []string) string {
+	for _, name := range configured {
+		if deck == name || strings.HasPrefix(deck, name+"::") {
+			return name
+		}
+	}
+	return ""
 }
 
 func (c *Client) GetCardInfo(cardID int64) (*CardInfo, error) {
+	cards, err := c.GetCardsInfo([]int64{cardID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("card not found: %d", cardID)
+	}
+	return cards[0], nil
+}
+
+// GetCardsInfo returns the cards in one request. Cards that no longer exist
+// are left out.
+func (c *Client) GetCardsInfo(cardIDs []int64) ([]*CardInfo, error) {
 	result, err := c.call("cardsInfo", map[string]interface{}{
-		"cards": []int64{cardID},
+		"cards": cardIDs,
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	var cards []struct {
-		CardID   int64  `json:"cardId"`
-		DeckName string `json:"deckName"`
-		Fields   map[string]struct {
-			Value string `json:"value"`
-			Order int    `json:"order"`
-		} `json:"fields"`
+		CardID    int64                `json:"cardId"`
+		DeckName  string               `json:"deckName"`
+		ModelName string               `json:"modelName"`
+		Mod       int64                `json:"mod"`
+		Fields    map[string]cardField `json:"fields"`
 	}
 	if err := json.Unmarshal(result, &cards); err != nil {
 		return nil, err
 	}
 
-	if len(cards) == 0 {
-		return nil, fmt.Errorf("card not found: %d", cardID)
+	infos := make([]*CardInfo, 0, len(cards))
+	for _, card := range cards {
+		// Missing cards come back as empty objects.
+		if card.CardID == 0 {
+			continue
+		}
+		infos = append(infos, c.mappings.cardInfo(card.CardID, card.DeckName, card.ModelName, card.Mod, card.Fields))
 	}
+	return infos, nil
+}
 
-	card := cards[0]
-	question, reading, answer := extractCardFields(card.Fields)
+// cardInfo picks the question, reading and answer among the card's fields.
+func (m fieldMappings) cardInfo(cardID int64, deck, modelName string, mod int64, fields map[string]cardField) *CardInfo {
+	var question, reading, answer string
+	if mapping, ok := m[modelName]; ok && fields[mapping.Question].Value != "" {
+		question, reading, answer = mappedCardFields(fields, mapping)
+	} else {
+		question, reading, answer = extractCardFields(fields)
+	}
 
-	return &CardInfo{
-		CardID:   card.CardID,
-		DeckName: card.DeckName,
-		Question: StripHTML(question),
-		Reading:  StripHTML(reading),
-		Answer:   StripHTML(answer),
-	}, nil
+	info := &CardInfo{
+		CardID:    cardID,
+		DeckName:  deck,
+		ModelName: modelName,
+		Question:  StripHTML(question),
+		Answer:    StripHTML(answer),
+		Mod:       mod,
+	}
+	// An empty reading stays empty instead of becoming "[media card]".
+	if reading != "" {
+		info.Reading = StripHTML(reading)
+	}
+	return info
 }
 
-func extractCardFields(fields map[string]struct {
+type cardField struct {
 	Value string `json:"value"`
 	Order int    `json:"order"`
-}) (question, reading, answer string) {
+}
+
+func mappedCardFields(fields map[string]cardField, mapping FieldMapping) (question, reading, answer string) {
+	return fields[mapping.Question].Value, fields[mapping.Reading].Value, fields[mapping.Answer].Value
+}
+
+func extractCardFields(fields map[string]cardField) (question, reading, answer string) {
 	// Common field names for question (front)
 	questionKeys := []string{"Word", "Front", "Expression", "Vocabulary", "Kanji", "Question"}
 	// Common field names for reading (furigana)
@@ -293,6 +581,29 @@ func (c *Client) AnswerCard(cardID int64, ease int) error {
 	return err
 }
 
+// Sync syncs the collection with AnkiWeb, as the Sync button in Anki does.
+func (c *Client) Sync() error {
+	_, err := c.call("sync", nil)
+	return err
+}
+
+// AnswerCards grades several cards at once and reports which cards were
+// found and answered.
+func (c *Client) AnswerCards(answers []Answer) ([]bool, error) {
+	result, err := c.call("answerCards", map[string]interface{}{
+		"answers": answers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var answered []bool
+	if err := json.Unmarshal(result, &answered); err != nil {
+		return nil, err
+	}
+	return answered, nil
+}
+
 var (
 	soundRegex = regexp.MustCompile(`\[sound:[^\]]+\]`)
 	brRegex    = regexp.MustCompile(`<br\s*/?>`)
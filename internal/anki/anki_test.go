@@ -0,0 +1,304 @@
+package anki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAnki answers AnkiConnect requests with the result of handle, or with
+// the API key error when key does not match. The actions of multi requests
+// are passed to handle one by one.
+func fakeAnki(t *testing.T, key string, handle func(action string, params json.RawMessage) any) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Action string          `json:"action"`
+			Key    string          `json:"key"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req.Key != key {
+			json.NewEncoder(w).Encode(map[string]any{"result": nil, "error": "valid api key must be provided"})
+			return
+		}
+		if req.Action == "multi" {
+			var multi struct {
+				Actions []struct {
+					Action string          `json:"action"`
+					Params json.RawMessage `json:"params"`
+				} `json:"actions"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params, &multi))
+
+			results := make([]any, len(multi.Actions))
+			for i, a := range multi.Actions {
+				results[i] = map[string]any{"result": handle(a.Action, a.Params), "error": nil}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"result": results, "error": nil})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": handle(req.Action, req.Params), "error": nil})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientSendsAPIKey(t *testing.T) {
+	server := fakeAnki(t, "secret", func(action string, params json.RawMessage) any {
+		return 6
+	})
+
+	assert.NoError(t, NewClient(server.URL, "secret").Ping())
+	assert.ErrorIs(t, NewClient(server.URL, "wrong").Ping(), ErrorUnauthorized)
+	assert.ErrorIs(t, NewClient(server.URL, "").Ping(), ErrorUnauthorized)
+}
+
+func TestClientForbidden(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewClient(server.URL, "").Ping()
+	assert.ErrorIs(t, err, ErrorForbidden)
+	assert.NotErrorIs(t, err, ErrorUnauthorized)
+}
+
+func TestClientDisconnected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	err := NewClient(server.URL, "").Ping()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrorUnauthorized)
+}
+
+func TestNewClientDefaultURL(t *testing.T) {
+	assert.Equal(t, DefaultURL, NewClient("", "").url)
+}
+
+func cardsInfoResult(modelName string, fields map[string]string) any {
+	cardFields := map[string]any{}
+	order := 0
+	for _, name := range []string{"Expression", "Meaning", "Reading", "Sentence"} {
+		if value, ok := fields[name]; ok {
+			cardFields[name] = map[string]any{"value": value, "order": order}
+			order++
+		}
+	}
+	return []map[string]any{{
+		"cardId":    int64(1),
+		"deckName":  "Japanese",
+		"modelName": modelName,
+		"fields":    cardFields,
+	}}
+}
+
+func TestGetCardInfoUsesFieldMapping(t *testing.T) {
+	fields := map[string]string{
+		"Expression": "雨",
+		"Meaning":    "雨が降る。",
+		"Reading":    "あめ",
+		"Sentence":   "rain",
+	}
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		return cardsInfoResult("Core", fields)
+	})
+
+	client := NewClient(server.URL, "")
+
+	// Without a mapping, the common field names are guessed.
+	card, err := client.GetCardInfo(1)
+	require.NoError(t, err)
+	assert.Equal(t, "雨が降る。", card.Answer)
+
+	client.SetFieldMapping("Core", FieldMapping{Question: "Expression", Reading: "Reading", Answer: "Sentence"})
+
+	card, err = client.GetCardInfo(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Core", card.ModelName)
+	assert.Equal(t, "雨", card.Question)
+	assert.Equal(t, "あめ", card.Reading)
+	assert.Equal(t, "rain", card.Answer)
+}
+
+func TestGetCardInfoWithoutReading(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		return cardsInfoResult("Basic", map[string]string{"Expression": "雨", "Meaning": "rain"})
+	})
+
+	client := NewClient(server.URL, "")
+	client.SetFieldMapping("Basic", FieldMapping{Question: "Expression", Answer: "Meaning"})
+
+	card, err := client.GetCardInfo(1)
+	require.NoError(t, err)
+	assert.Empty(t, card.Reading)
+	assert.Equal(t, "rain", card.Answer)
+}
+
+func TestGetDeckNoteTypes(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "modelNames":
+			return []string{"Core", "Basic"}
+		case "findNotes":
+			var p struct {
+				Query string `json:"query"`
+			}
+			json.Unmarshal(params, &p)
+			if p.Query == `deck:"Japanese" note:"Core"` {
+				return []int64{1, 2}
+			}
+			return []int64{}
+		case "modelFieldNames":
+			return []string{"Expression", "Reading", "Meaning"}
+		}
+		return nil
+	})
+
+	noteTypes, err := NewClient(server.URL, "").GetDeckNoteTypes("Japanese")
+	require.NoError(t, err)
+	assert.Equal(t, []NoteType{{Name: "Core", Fields: []string{"Expression", "Reading", "Meaning"}}}, noteTypes)
+}
+
+func TestGetCardsInfoSkipsMissingCards(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		card := cardsInfoResult("Basic", map[string]string{"Expression": "雨", "Meaning": "rain"}).([]map[string]any)[0]
+		card["mod"] = int64(1700000000)
+		return []map[string]any{card, {}}
+	})
+
+	cards, err := NewClient(server.URL, "").GetCardsInfo([]int64{1, 2})
+	require.NoError(t, err)
+	require.Len(t, cards, 1)
+	assert.Equal(t, int64(1700000000), cards[0].Mod)
+}
+
+func TestGetDueCardsLimitsNewCardsPerDeck(t *testing.T) {
+	var queries []string
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "findCards":
+			var p struct {
+				Query string `json:"query"`
+			}
+			json.Unmarshal(params, &p)
+			queries = append(queries, p.Query)
+			if p.Query == "(tag:core) is:due" {
+				return []int64{1}
+			}
+			return []int64{10, 11, 12, 20, 21}
+		case "getDecks":
+			return map[string][]int64{"Japanese::Core": {10, 11, 12}, "Japanese::Kanji": {20, 21}}
+		case "getDeckStats":
+			return map[string]any{
+				"1": map[string]any{"name": "Japanese::Core", "new_count": 2},
+				"2": map[string]any{"name": "Japanese::Kanji", "new_count": 0},
+			}
+		}
+		return nil
+	})
+
+	client := NewClient(server.URL, "")
+	src := Source{Query: "tag:core"}
+
+	cards, err := client.GetDueCards(src)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 10, 11}, cards)
+	assert.Equal(t, []string{"(tag:core) is:due", "(tag:core) is:new"}, queries)
+
+	count, err := client.GetDueCount(src)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestGetDueCardsCapsSubdecksByParent(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "findCards":
+			var p struct {
+				Query string `json:"query"`
+			}
+			json.Unmarshal(params, &p)
+			if p.Query == `deck:"Japanese" is:due` {
+				return []int64{1}
+			}
+			return []int64{10, 11, 20, 21}
+		case "deckNames":
+			return []string{"Japanese", "Japanese::Core", "Japanese::Kanji"}
+		case "getDecks":
+			return map[string][]int64{"Japanese::Core": {10, 11}, "Japanese::Kanji": {20, 21}}
+		case "getDeckStats":
+			return map[string]any{
+				"1": map[string]any{"name": "Japanese", "new_count": 3, "review_count": 1},
+				"2": map[string]any{"name": "Japanese::Core", "new_count": 2},
+				"3": map[string]any{"name": "Japanese::Kanji", "new_count": 2},
+			}
+		}
+		return nil
+	})
+
+	client := NewClient(server.URL, "")
+	src := Source{Decks: []string{"Japanese"}}
+
+	cards, err := client.GetDueCards(src)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 10, 11, 20}, cards)
+
+	count, err := client.GetDueCount(src)
+	require.NoError(t, err)
+	assert.Equal(t, len(cards), count)
+}
+
+func TestGetDueCardsReportsNewCardLimitFailure(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "findCards":
+			return []int64{1}
+		case "getDecks":
+			return "not a deck map"
+		}
+		return nil
+	})
+
+	_, err := NewClient(server.URL, "").GetDueCards(Source{Decks: []string{"Japanese"}})
+	assert.Error(t, err)
+}
+
+func TestGetDecksWithStatsBatchesStats(t *testing.T) {
+	statsCalls := 0
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "deckNamesAndIds":
+			return map[string]int64{"Core": 1, "Kanji": 2}
+		case "getDeckStats":
+			statsCalls++
+			return map[string]any{
+				"1": map[string]any{"name": "Core", "new_count": 5, "learn_count": 1, "review_count": 10},
+				"2": map[string]any{"name": "Kanji", "review_count": 3},
+			}
+		}
+		return nil
+	})
+
+	decks, err := NewClient(server.URL, "").GetDecksWithStats()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []DeckInfo{{Name: "Core", DueCount: 16}, {Name: "Kanji", DueCount: 3}}, decks)
+	assert.Equal(t, 1, statsCalls)
+}
+
+func TestSync(t *testing.T) {
+	var actions []string
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		actions = append(actions, action)
+		return nil
+	})
+
+	require.NoError(t, NewClient(server.URL, "").Sync())
+	assert.Equal(t, []string{"sync"}, actions)
+}
@@ -0,0 +1,157 @@
+package anki
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrorReadOnly is returned when answering cards read from the collection
+// file, which only AnkiConnect can change.
+var ErrorReadOnly = errors.New("anki: collection is read-only, answers need AnkiConnect")
+
+// Backend is where cards are reviewed from: AnkiConnect, or the collection
+// file while Anki is closed.
+type Backend interface {
+	Ping() error
+	// ReadOnly reports whether the last call was served by the collection
+	// file, in which case answers return ErrorReadOnly.
+	ReadOnly() bool
+	GetDecksWithStats() ([]DeckInfo, error)
+	GetDeckNoteTypes(deck string) ([]NoteType, error)
+	GetDueCards(src Source) ([]int64, error)
+	GetDueCount(src Source) (int, error)
+	GetCardsInfo(cardIDs []int64) ([]*CardInfo, error)
+	AnswerCard(cardID int64, ease int) error
+	AnswerCards(answers []Answer) ([]bool, error)
+	Sync() error
+}
+
+// CollectionFile is the name of the collection in an Anki profile folder.
+const CollectionFile = "collection.anki2"
+
+// NewBackend returns the client, falling back to the collection of the Anki
+// profile folder when AnkiConnect cannot be reached. The collection uses the
+// field mappings of the client.
+func NewBackend(client *Client, profilePath string) Backend {
+	if profilePath == "" {
+		return client
+	}
+
+	collection := NewCollection(filepath.Join(expandHome(profilePath), CollectionFile))
+	collection.mappings = client.mappings
+	return &fallbackBackend{connect: client, collection: collection}
+}
+
+// expandHome replaces a leading ~ with the home folder, which the shell does
+// not do for paths in the config.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func (c *Client) ReadOnly() bool {
+	return false
+}
+
+// fallbackBackend uses the collection for each call AnkiConnect cannot
+// answer because it is not running.
+type fallbackBackend struct {
+	connect    *Client
+	collection *Collection
+	readOnly   atomic.Bool
+}
+
+// useCollection records whether err means AnkiConnect is unreachable. Other
+// errors, like a rejected API key, are returned as they are.
+func (b *fallbackBackend) useCollection(err error) bool {
+	var urlErr *url.Error
+	unreachable := errors.As(err, &urlErr)
+	b.readOnly.Store(unreachable)
+	return unreachable
+}
+
+func (b *fallbackBackend) ReadOnly() bool {
+	return b.readOnly.Load()
+}
+
+func (b *fallbackBackend) Ping() error {
+	err := b.connect.Ping()
+	if b.useCollection(err) {
+		return b.collection.Ping()
+	}
+	return err
+}
+
+func (b *fallbackBackend) GetDecksWithStats() ([]DeckInfo, error) {
+	decks, err := b.connect.GetDecksWithStats()
+	if b.useCollection(err) {
+		return b.collection.GetDecksWithStats()
+	}
+	return decks, err
+}
+
+func (b *fallbackBackend) GetDeckNoteTypes(deck string) ([]NoteType, error) {
+	noteTypes, err := b.connect.GetDeckNoteTypes(deck)
+	if b.useCollection(err) {
+		return b.collection.GetDeckNoteTypes(deck)
+	}
+	return noteTypes, err
+}
+
+func (b *fallbackBackend) GetDueCards(src Source) ([]int64, error) {
+	cards, err := b.connect.GetDueCards(src)
+	if b.useCollection(err) {
+		return b.collection.GetDueCards(src)
+	}
+	return cards, err
+}
+
+func (b *fallbackBackend) GetDueCount(src Source) (int, error) {
+	count, err := b.connect.GetDueCount(src)
+	if b.useCollection(err) {
+		return b.collection.GetDueCount(src)
+	}
+	return count, err
+}
+
+func (b *fallbackBackend) GetCardsInfo(cardIDs []int64) ([]*CardInfo, error) {
+	cards, err := b.connect.GetCardsInfo(cardIDs)
+	if b.useCollection(err) {
+		return b.collection.GetCardsInfo(cardIDs)
+	}
+	return cards, err
+}
+
+func (b *fallbackBackend) AnswerCard(cardID int64, ease int) error {
+	err := b.connect.AnswerCard(cardID, ease)
+	if b.useCollection(err) {
+		return b.collection.AnswerCard(cardID, ease)
+	}
+	return err
+}
+
+func (b *fallbackBackend) Sync() error {
+	err := b.connect.Sync()
+	if b.useCollection(err) {
+		return b.collection.Sync()
+	}
+	return err
+}
+
+func (b *fallbackBackend) AnswerCards(answers []Answer) ([]bool, error) {
+	answered, err := b.connect.AnswerCards(answers)
+	if b.useCollection(err) {
+		return b.collection.AnswerCards(answers)
+	}
+	return answered, err
+}
@@ -0,0 +1,496 @@
+package anki
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrorUnsupportedQuery is returned for sources with an Anki search, which
+// only Anki itself can run.
+var ErrorUnsupportedQuery = errors.New("anki: searches need AnkiConnect, the collection file only supports decks")
+
+// collectionNewPerDay is the daily new card limit of each deck when reading
+// the collection file, whose deck options are not read.
+const collectionNewPerDay = 20
+
+// Anki card queues.
+const (
+	queueNew      = 0
+	queueLearn    = 1
+	queueReview   = 2
+	queueDayLearn = 3
+)
+
+// Collection reads the cards of an Anki collection file. The file is opened
+// read-only for each call, as Anki may replace it when it syncs.
+type Collection struct {
+	path     string
+	mappings fieldMappings
+	now      func() time.Time
+}
+
+func NewCollection(path string) *Collection {
+	return &Collection{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+// SetFieldMapping makes cards of the note type use the given fields instead
+// of guessing them from common field names.
+func (c *Collection) SetFieldMapping(noteType string, mapping FieldMapping) {
+	if c.mappings == nil {
+		c.mappings = make(fieldMappings)
+	}
+	c.mappings[noteType] = mapping
+}
+
+func (c *Collection) open() (*sql.DB, error) {
+	// sql.Open would create a missing file.
+	if _, err := os.Stat(c.path); err != nil {
+		return nil, fmt.Errorf("error opening anki collection: %s", err)
+	}
+	dsn := (&url.URL{Scheme: "file", Path: c.path, RawQuery: "mode=ro"}).String()
+	return sql.Open("sqlite3", dsn)
+}
+
+// collectionInfo holds the decks and note types of a collection. Recent Anki
+// versions keep them in their own tables, older ones as JSON in col.
+type collectionInfo struct {
+	crt       int64
+	decks     map[int64]string
+	noteTypes map[int64]NoteType
+}
+
+func loadCollectionInfo(db *sql.DB) (*collectionInfo, error) {
+	info := &collectionInfo{
+		decks:     make(map[int64]string),
+		noteTypes: make(map[int64]NoteType),
+	}
+
+	var decksJSON, modelsJSON string
+	err := db.QueryRow(`SELECT crt, decks, models FROM col`).Scan(&info.crt, &decksJSON, &modelsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki collection: %s", err)
+	}
+
+	var tables int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'notetypes'`).Scan(&tables)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki collection: %s", err)
+	}
+
+	if tables == 0 {
+		return info, info.loadLegacy(decksJSON, modelsJSON)
+	}
+	return info, info.load(db)
+}
+
+func (info *collectionInfo) load(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, name FROM decks`)
+	if err != nil {
+		return fmt.Errorf("error reading anki decks: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		// Deck levels are separated by 0x1f instead of "::".
+		info.decks[id] = strings.ReplaceAll(name, "\x1f", "::")
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query(`
+		SELECT nt.id, nt.name, f.name
+		FROM notetypes nt
+		JOIN fields f ON f.ntid = nt.id
+		ORDER BY nt.id, f.ord`)
+	if err != nil {
+		return fmt.Errorf("error reading anki note types: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name, field string
+		if err := rows.Scan(&id, &name, &field); err != nil {
+			return err
+		}
+		nt := info.noteTypes[id]
+		nt.Name = name
+		nt.Fields = append(nt.Fields, field)
+		info.noteTypes[id] = nt
+	}
+	return rows.Err()
+}
+
+func (info *collectionInfo) loadLegacy(decksJSON, modelsJSON string) error {
+	var decks map[string]struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return fmt.Errorf("error reading anki decks: %s", err)
+	}
+	for id, d := range decks {
+		deckID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error reading anki decks: %s", err)
+		}
+		info.decks[deckID] = d.Name
+	}
+
+	var models map[string]struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return fmt.Errorf("error reading anki note types: %s", err)
+	}
+	for id, m := range models {
+		modelID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error reading anki note types: %s", err)
+		}
+		sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Ord < m.Fields[j].Ord })
+
+		nt := NoteType{Name: m.Name}
+		for _, f := range m.Fields {
+			nt.Fields = append(nt.Fields, f.Name)
+		}
+		info.noteTypes[modelID] = nt
+	}
+	return nil
+}
+
+// deckIDs returns the decks of the source with their subdecks, like a
+// deck:"X" search.
+func (info *collectionInfo) deckIDs(src Source) ([]int64, error) {
+	if src.Query != "" {
+		return nil, ErrorUnsupportedQuery
+	}
+
+	var ids []int64
+	for id, name := range info.decks {
+		for _, d := range src.Decks {
+			if name == d || strings.HasPrefix(name, d+"::") {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// today is the number of days since the collection was created, which is
+// what review cards are due against.
+func (info *collectionInfo) today(now time.Time) int64 {
+	return (now.Unix() - info.crt) / 86400
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// Ping checks that the collection file can be read.
+func (c *Collection) Ping() error {
+	db, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var crt int64
+	if err := db.QueryRow(`SELECT crt FROM col`).Scan(&crt); err != nil {
+		return fmt.Errorf("error reading anki collection: %s", err)
+	}
+	return nil
+}
+
+func (c *Collection) ReadOnly() bool {
+	return true
+}
+
+func (c *Collection) GetDecksWithStats() ([]DeckInfo, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info, err := loadCollectionInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var decks []DeckInfo
+	for _, name := range info.decks {
+		cards, err := c.dueCards(db, info, Source{Decks: []string{name}})
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, DeckInfo{Name: name, DueCount: len(cards)})
+	}
+	return decks, nil
+}
+
+func (c *Collection) GetDeckNoteTypes(deck string) ([]NoteType, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info, err := loadCollectionInfo(db)
+	if err != nil {
+		return nil, err
+	}
+	deckIDs, err := info.deckIDs(Source{Decks: []string{deck}})
+	if err != nil || len(deckIDs) == 0 {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT n.mid
+		FROM cards c
+		JOIN notes n ON n.id = c.nid
+		WHERE c.did IN (`+placeholders(len(deckIDs))+`)`,
+		int64Args(deckIDs)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki note types: %s", err)
+	}
+	defer rows.Close()
+
+	var noteTypes []NoteType
+	for rows.Next() {
+		var mid int64
+		if err := rows.Scan(&mid); err != nil {
+			return nil, err
+		}
+		if nt, ok := info.noteTypes[mid]; ok {
+			noteTypes = append(noteTypes, nt)
+		}
+	}
+	sort.Slice(noteTypes, func(i, j int) bool { return noteTypes[i].Name < noteTypes[j].Name })
+	return noteTypes, rows.Err()
+}
+
+func (c *Collection) GetDueCards(src Source) ([]int64, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info, err := loadCollectionInfo(db)
+	if err != nil {
+		return nil, err
+	}
+	return c.dueCards(db, info, src)
+}
+
+func (c *Collection) GetDueCount(src Source) (int, error) {
+	cards, err := c.GetDueCards(src)
+	if err != nil {
+		return 0, err
+	}
+	return len(cards), nil
+}
+
+// dueCards returns the learning and review cards due now, followed by the
+// new cards each deck may still introduce today.
+func (c *Collection) dueCards(db *sql.DB, info *collectionInfo, src Source) ([]int64, error) {
+	deckIDs, err := info.deckIDs(src)
+	if err != nil || len(deckIDs) == 0 {
+		return nil, err
+	}
+
+	now := c.now()
+	today := info.today(now)
+	in := placeholders(len(deckIDs))
+
+	args := append(int64Args(deckIDs), queueReview, queueDayLearn, today, queueLearn, now.Unix())
+	due, err := queryCardIDs(db, `
+		SELECT id, did
+		FROM cards
+		WHERE did IN (`+in+`)
+			AND ((queue IN (?, ?) AND due <= ?) OR (queue = ? AND due <= ?))
+		ORDER BY queue, due`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	newCards, err := queryCardIDs(db, `
+		SELECT id, did
+		FROM cards
+		WHERE did IN (`+in+`) AND queue = ?
+		ORDER BY due, ord`,
+		append(int64Args(deckIDs), queueNew)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// New cards first reviewed today count against the limit.
+	dayStart := (info.crt + today*86400) * 1000
+	introduced := make(map[int64]int)
+	rows, err := db.Query(`
+		SELECT c.did, COUNT(DISTINCT r.cid)
+		FROM revlog r
+		JOIN cards c ON c.id = r.cid
+		WHERE r.type = 0 AND r.id >= ?
+		GROUP BY c.did`,
+		dayStart,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki review log: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var did int64
+		var count int
+		if err := rows.Scan(&did, &count); err != nil {
+			return nil, err
+		}
+		introduced[did] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cards := make([]int64, 0, len(due))
+	for _, card := range due {
+		cards = append(cards, card.id)
+	}
+	for _, card := range newCards {
+		if introduced[card.deck] < collectionNewPerDay {
+			cards = append(cards, card.id)
+			introduced[card.deck]++
+		}
+	}
+	return cards, nil
+}
+
+type collectionCard struct {
+	id   int64
+	deck int64
+}
+
+func queryCardIDs(db *sql.DB, query string, args ...interface{}) ([]collectionCard, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki cards: %s", err)
+	}
+	defer rows.Close()
+
+	var cards []collectionCard
+	for rows.Next() {
+		var card collectionCard
+		if err := rows.Scan(&card.id, &card.deck); err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// GetCardsInfo returns the cards in the order asked. Cards that no longer
+// exist are left out.
+func (c *Collection) GetCardsInfo(cardIDs []int64) ([]*CardInfo, error) {
+	if len(cardIDs) == 0 {
+		return nil, nil
+	}
+
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info, err := loadCollectionInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT c.id, c.did, c.mod, n.mid, n.flds
+		FROM cards c
+		JOIN notes n ON n.id = c.nid
+		WHERE c.id IN (`+placeholders(len(cardIDs))+`)`,
+		int64Args(cardIDs)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading anki cards: %s", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]*CardInfo, len(cardIDs))
+	for rows.Next() {
+		var id, did, mod, mid int64
+		var flds string
+		if err := rows.Scan(&id, &did, &mod, &mid, &flds); err != nil {
+			return nil, err
+		}
+
+		noteType := info.noteTypes[mid]
+		fields := make(map[string]cardField, len(noteType.Fields))
+		for i, value := range strings.Split(flds, "\x1f") {
+			if i < len(noteType.Fields) {
+				fields[noteType.Fields[i]] = cardField{Value: value, Order: i}
+			}
+		}
+		byID[id] = c.mappings.cardInfo(id, info.decks[did], noteType.Name, mod, fields)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cards := make([]*CardInfo, 0, len(byID))
+	for _, id := range cardIDs {
+		if card, ok := byID[id]; ok {
+			cards = append(cards, card)
+		}
+	}
+	return cards, nil
+}
+
+func (c *Collection) AnswerCard(cardID int64, ease int) error {
+	return ErrorReadOnly
+}
+
+func (c *Collection) AnswerCards(answers []Answer) ([]bool, error) {
+	return nil, ErrorReadOnly
+}
+
+func (c *Collection) Sync() error {
+	return ErrorReadOnly
+}
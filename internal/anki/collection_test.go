@@ -0,0 +1,197 @@
+package anki
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var collectionNow = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// newTestCollection writes a small collection in the current schema, or the
+// legacy one keeping decks and note types as JSON in col.
+func newTestCollection(t *testing.T, legacy bool) *Collection {
+	path := filepath.Join(t.TempDir(), CollectionFile)
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Created ten days before now.
+	crt := collectionNow.Unix() - 10*86400 - 3600
+
+	stmts := []string{
+		`CREATE TABLE col (id INTEGER PRIMARY KEY, crt INTEGER, decks TEXT, models TEXT)`,
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY, mid INTEGER, flds TEXT)`,
+		`CREATE TABLE cards (id INTEGER PRIMARY KEY, nid INTEGER, did INTEGER, ord INTEGER, mod INTEGER, queue INTEGER, due INTEGER)`,
+		`CREATE TABLE revlog (id INTEGER PRIMARY KEY, cid INTEGER, type INTEGER)`,
+	}
+	if legacy {
+		stmts = append(stmts, `INSERT INTO col VALUES (1, ?, '{"1": {"name": "Default"}, "2": {"name": "Japanese"}, "3": {"name": "Japanese::N5"}, "4": {"name": "Other"}}', '{"10": {"name": "Basic", "flds": [{"name": "Back", "ord": 1}, {"name": "Front", "ord": 0}]}, "11": {"name": "Vocab", "flds": [{"name": "Word", "ord": 0}, {"name": "Reading", "ord": 1}, {"name": "Meaning", "ord": 2}]}}')`)
+	} else {
+		stmts = append(stmts,
+			`INSERT INTO col VALUES (1, ?, '', '')`,
+			`CREATE TABLE decks (id INTEGER PRIMARY KEY, name TEXT)`,
+			`CREATE TABLE notetypes (id INTEGER PRIMARY KEY, name TEXT)`,
+			`CREATE TABLE fields (ntid INTEGER, ord INTEGER, name TEXT)`,
+			"INSERT INTO decks VALUES (1, 'Default'), (2, 'Japanese'), (3, 'Japanese\x1fN5'), (4, 'Other')",
+			`INSERT INTO notetypes VALUES (10, 'Basic'), (11, 'Vocab')`,
+			`INSERT INTO fields VALUES (10, 1, 'Back'), (10, 0, 'Front'), (11, 0, 'Word'), (11, 1, 'Reading'), (11, 2, 'Meaning')`,
+		)
+	}
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt, crt)
+		require.NoError(t, err, stmt)
+	}
+
+	_, err = db.Exec("INSERT INTO notes VALUES (1, 11, '猫\x1fねこ\x1fcat'), (2, 10, 'dog\x1f<b>犬</b>')")
+	require.NoError(t, err)
+
+	cards := [][]any{
+		{101, 1, 2, 0, 1700, queueReview, 9},                         // due yesterday
+		{102, 2, 3, 0, 1701, queueReview, 11},                        // due tomorrow
+		{103, 2, 3, 0, 1702, queueLearn, collectionNow.Unix() - 60},  // learning, due
+		{104, 2, 4, 0, 1703, queueReview, 0},                         // another deck
+		{110, 2, 2, 0, 1704, queueLearn, collectionNow.Unix() + 600}, // learning, later
+	}
+	// More new cards than the daily limit.
+	for i := 0; i < 25; i++ {
+		cards = append(cards, []any{200 + i, 2, 2, 0, 1800, queueNew, i})
+	}
+	for _, c := range cards {
+		_, err := db.Exec(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, ?, ?)`, c...)
+		require.NoError(t, err)
+	}
+
+	// Three new cards were started today and one yesterday.
+	dayStart := (crt + 10*86400) * 1000
+	for i, cid := range []int{110, 201, 202} {
+		_, err := db.Exec(`INSERT INTO revlog VALUES (?, ?, 0)`, dayStart+int64(i)+1, cid)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`INSERT INTO revlog VALUES (?, 203, 0)`, dayStart-1000)
+	require.NoError(t, err)
+
+	collection := NewCollection(path)
+	collection.now = func() time.Time { return collectionNow }
+	return collection
+}
+
+func TestCollectionDueCards(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		c := newTestCollection(t, legacy)
+
+		cards, err := c.GetDueCards(Source{Decks: []string{"Japanese"}})
+		require.NoError(t, err)
+
+		// Learning and review cards of the deck and its subdecks, then
+		// the new cards left for today.
+		require.Len(t, cards, 2+collectionNewPerDay-3)
+		assert.Equal(t, []int64{103, 101, 200, 201}, cards[:4])
+
+		count, err := c.GetDueCount(Source{Decks: []string{"Japanese::N5"}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		count, err = c.GetDueCount(Source{Decks: []string{"Missing"}})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	}
+}
+
+func TestCollectionDecksWithStats(t *testing.T) {
+	c := newTestCollection(t, false)
+
+	decks, err := c.GetDecksWithStats()
+	require.NoError(t, err)
+
+	due := make(map[string]int)
+	for _, d := range decks {
+		due[d.Name] = d.DueCount
+	}
+	assert.Equal(t, map[string]int{
+		"Default":      0,
+		"Japanese":     19,
+		"Japanese::N5": 1,
+		"Other":        1,
+	}, due)
+
+	noteTypes, err := c.GetDeckNoteTypes("Japanese")
+	require.NoError(t, err)
+	assert.Equal(t, []NoteType{
+		{Name: "Basic", Fields: []string{"Front", "Back"}},
+		{Name: "Vocab", Fields: []string{"Word", "Reading", "Meaning"}},
+	}, noteTypes)
+}
+
+func TestCollectionCardsInfo(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		c := newTestCollection(t, legacy)
+
+		cards, err := c.GetCardsInfo([]int64{103, 999, 101})
+		require.NoError(t, err)
+		require.Len(t, cards, 2)
+
+		assert.Equal(t, &CardInfo{
+			CardID:    103,
+			DeckName:  "Japanese::N5",
+			ModelName: "Basic",
+			Question:  "dog",
+			Answer:    "犬",
+			Mod:       1702,
+		}, cards[0])
+		assert.Equal(t, "猫", cards[1].Question)
+		assert.Equal(t, "ねこ", cards[1].Reading)
+		assert.Equal(t, "cat", cards[1].Answer)
+
+		c.SetFieldMapping("Basic", FieldMapping{Question: "Back", Answer: "Front"})
+		cards, err = c.GetCardsInfo([]int64{103})
+		require.NoError(t, err)
+		assert.Equal(t, "犬", cards[0].Question)
+		assert.Equal(t, "dog", cards[0].Answer)
+	}
+}
+
+func TestCollectionReadOnly(t *testing.T) {
+	c := newTestCollection(t, false)
+
+	assert.NoError(t, c.Ping())
+	assert.True(t, c.ReadOnly())
+	assert.ErrorIs(t, c.AnswerCard(101, 3), ErrorReadOnly)
+	assert.ErrorIs(t, c.Sync(), ErrorReadOnly)
+
+	_, err := c.GetDueCards(Source{Query: "is:due"})
+	assert.ErrorIs(t, err, ErrorUnsupportedQuery)
+
+	assert.Error(t, NewCollection(filepath.Join(t.TempDir(), CollectionFile)).Ping())
+}
+
+func TestBackendFallsBackToCollection(t *testing.T) {
+	c := newTestCollection(t, false)
+
+	// AnkiConnect is not running.
+	server := httptest.NewServer(nil)
+	server.Close()
+
+	backend := NewBackend(NewClient(server.URL, ""), filepath.Dir(c.path))
+	assert.False(t, backend.ReadOnly())
+
+	require.NoError(t, backend.Ping())
+	assert.True(t, backend.ReadOnly())
+
+	cards, err := backend.GetCardsInfo([]int64{101})
+	require.NoError(t, err)
+	assert.Equal(t, "猫", cards[0].Question)
+	assert.ErrorIs(t, backend.AnswerCard(101, 3), ErrorReadOnly)
+
+	// The profile path may start with ~, as in the README.
+	profile := filepath.Dir(c.path)
+	t.Setenv("HOME", filepath.Dir(profile))
+	backend = NewBackend(NewClient(server.URL, ""), "~/"+filepath.Base(profile))
+	require.NoError(t, backend.Ping())
+	assert.True(t, backend.ReadOnly())
+}
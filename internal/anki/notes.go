@@ -0,0 +1,241 @@
+package anki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NoteTag marks the notes keiko adds to Anki.
+const NoteTag = "keiko"
+
+// ErrorDuplicate means the deck already has a note with the same first field.
+var ErrorDuplicate = errors.New("anki: duplicate note")
+
+// Note is a note to add to Anki, with its field values by field name.
+type Note struct {
+	Deck     string
+	NoteType string
+	Fields   map[string]string
+	Tags     []string
+}
+
+// VocabNote is what keiko knows about a word it adds to Anki.
+type VocabNote struct {
+	Word     string
+	Reading  string
+	Meaning  string
+	Sentence string
+}
+
+func (n Note) params(allowDuplicate bool) map[string]interface{} {
+	return map[string]interface{}{
+		"deckName":  n.Deck,
+		"modelName": n.NoteType,
+		"fields":    n.Fields,
+		"tags":      n.Tags,
+		"options": map[string]interface{}{
+			"allowDuplicate": allowDuplicate,
+			"duplicateScope": "deck",
+		},
+	}
+}
+
+// GetNoteTypeFields lists the fields of a note type in order.
+func (c *Client) GetNoteTypeFields(noteType string) ([]string, error) {
+	result, err := c.call("modelFieldNames", map[string]interface{}{
+		"modelName": noteType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// NewVocabNote fills the fields of the note type from the word, using the
+// note type's field mapping when there is one. Without a mapping the word,
+// reading, meaning and sentence go to the fields in order, and whatever does
+// not fit is appended to the last field.
+func (c *Client) NewVocabNote(deck, noteType string, word VocabNote) (Note, error) {
+	notes, err := c.NewVocabNotes(deck, noteType, []VocabNote{word})
+	if err != nil {
+		return Note{}, err
+	}
+	return notes[0], nil
+}
+
+// NewVocabNotes is NewVocabNote for several words, looking up the fields of
+// the note type once.
+func (c *Client) NewVocabNotes(deck, noteType string, words []VocabNote) ([]Note, error) {
+	fields, err := c.GetNoteTypeFields(noteType)
+	if err != nil {
+		return nil, err
+	}
+	return c.VocabNotes(deck, noteType, fields, words)
+}
+
+// VocabNotes is NewVocabNotes for a note type whose fields are known.
+func (c *Client) VocabNotes(deck, noteType string, fields []string, words []VocabNote) ([]Note, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("note type %s has no fields", noteType)
+	}
+
+	notes := make([]Note, len(words))
+	for i, word := range words {
+		notes[i] = c.vocabNote(deck, noteType, fields, word)
+	}
+	return notes, nil
+}
+
+func (c *Client) vocabNote(deck, noteType string, fields []string, word VocabNote) Note {
+	note := Note{
+		Deck:     deck,
+		NoteType: noteType,
+		Fields:   make(map[string]string, len(fields)),
+		Tags:     []string{NoteTag},
+	}
+	for _, f := range fields {
+		note.Fields[f] = ""
+	}
+
+	if mapping, ok := c.mappings[noteType]; ok && mapping.Question != "" {
+		setOrAppend(note.Fields, mapping.Question, fields[0], word.Word)
+		setOrAppend(note.Fields, mapping.Reading, mapping.Answer, word.Reading)
+		setOrAppend(note.Fields, mapping.Answer, mapping.Question, word.Meaning)
+		setOrAppend(note.Fields, mapping.Sentence, mapping.Answer, word.Sentence)
+		return note
+	}
+
+	values := []string{word.Word, word.Reading, word.Meaning, word.Sentence}
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		field := fields[min(i, len(fields)-1)]
+		setOrAppend(note.Fields, field, field, v)
+	}
+	return note
+}
+
+// setOrAppend appends value on a new line to field, or to fallback when the
+// note type has no such field.
+func setOrAppend(fields map[string]string, field, fallback, value string) {
+	if value == "" {
+		return
+	}
+	if _, ok := fields[field]; !ok || field == "" {
+		field = fallback
+	}
+	if fields[field] == "" {
+		fields[field] = value
+		return
+	}
+	fields[field] = strings.Join([]string{fields[field], value}, "<br>")
+}
+
+// CanAddNote returns nil when Anki would accept the note, ErrorDuplicate when
+// the deck already has it, or the reason Anki gives, like a missing deck or
+// an empty first field.
+func (c *Client) CanAddNote(note Note) error {
+	result, err := c.call("canAddNotesWithErrorDetail", map[string]interface{}{
+		"notes": []interface{}{note.params(false)},
+	})
+	if err != nil {
+		return err
+	}
+
+	var details []struct {
+		CanAdd bool   `json:"canAdd"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(result, &details); err != nil {
+		return err
+	}
+	switch {
+	case len(details) == 0:
+		return errors.New("anki: no answer for the note")
+	case details[0].CanAdd:
+		return nil
+	case strings.Contains(details[0].Error, "duplicate"):
+		return ErrorDuplicate
+	default:
+		return fmt.Errorf("anki: %s", details[0].Error)
+	}
+}
+
+// CanAddNotes is CanAddNote for several notes, in order.
+func (c *Client) CanAddNotes(notes []Note) ([]bool, error) {
+	params := make([]interface{}, len(notes))
+	for i, n := range notes {
+		params[i] = n.params(false)
+	}
+	result, err := c.call("canAddNotes", map[string]interface{}{
+		"notes": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ok []bool
+	if err := json.Unmarshal(result, &ok); err != nil {
+		return nil, err
+	}
+	return ok, nil
+}
+
+// CreateDeck creates the deck, doing nothing when it exists.
+func (c *Client) CreateDeck(name string) error {
+	_, err := c.call("createDeck", map[string]interface{}{
+		"deck": name,
+	})
+	return err
+}
+
+// AddNote adds the note and returns its ID.
+func (c *Client) AddNote(note Note, allowDuplicate bool) (int64, error) {
+	result, err := c.call("addNote", map[string]interface{}{
+		"note": note.params(allowDuplicate),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := json.Unmarshal(result, &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// AddNotes adds the notes, rejecting duplicates, and returns their IDs in
+// order. Notes Anki did not add have ID 0.
+func (c *Client) AddNotes(notes []Note) ([]int64, error) {
+	params := make([]interface{}, len(notes))
+	for i, n := range notes {
+		params[i] = n.params(false)
+	}
+	result, err := c.call("addNotes", map[string]interface{}{
+		"notes": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []*int64
+	if err := json.Unmarshal(result, &ids); err != nil {
+		return nil, err
+	}
+	added := make([]int64, len(ids))
+	for i, id := range ids {
+		if id != nil {
+			added[i] = *id
+		}
+	}
+	return added, nil
+}
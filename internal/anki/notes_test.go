@@ -0,0 +1,116 @@
+package anki
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ame = VocabNote{Word: "雨", Reading: "あめ", Meaning: "rain", Sentence: "雨が降っています。"}
+
+func fieldNamesServer(t *testing.T, fields []string) *Client {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		return fields
+	})
+	return NewClient(server.URL, "")
+}
+
+func TestNewVocabNoteFillsFieldsInOrder(t *testing.T) {
+	client := fieldNamesServer(t, []string{"Front", "Back"})
+
+	note, err := client.NewVocabNote("Japanese", "Basic", ame)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Front": "雨",
+		"Back":  "あめ<br>rain<br>雨が降っています。",
+	}, note.Fields)
+	assert.Equal(t, []string{NoteTag}, note.Tags)
+}
+
+func TestNewVocabNoteUsesFieldMapping(t *testing.T) {
+	client := fieldNamesServer(t, []string{"Expression", "Meaning", "Reading", "Example"})
+	client.SetFieldMapping("Core", FieldMapping{Question: "Expression", Reading: "Reading", Answer: "Meaning", Sentence: "Example"})
+
+	note, err := client.NewVocabNote("Japanese", "Core", ame)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Expression": "雨",
+		"Meaning":    "rain",
+		"Reading":    "あめ",
+		"Example":    "雨が降っています。",
+	}, note.Fields)
+}
+
+func TestNewVocabNoteWithoutSentenceField(t *testing.T) {
+	client := fieldNamesServer(t, []string{"Expression", "Meaning"})
+	client.SetFieldMapping("Core", FieldMapping{Question: "Expression", Answer: "Meaning"})
+
+	note, err := client.NewVocabNote("Japanese", "Core", ame)
+	require.NoError(t, err)
+	assert.Equal(t, "あめ<br>rain<br>雨が降っています。", note.Fields["Meaning"])
+}
+
+func TestAddNote(t *testing.T) {
+	var allowDuplicate []bool
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		switch action {
+		case "canAddNotesWithErrorDetail":
+			return []any{map[string]any{"canAdd": false, "error": "cannot create note because it is a duplicate"}}
+		case "addNote":
+			var p struct {
+				Note struct {
+					Options struct {
+						AllowDuplicate bool `json:"allowDuplicate"`
+					} `json:"options"`
+				} `json:"note"`
+			}
+			require.NoError(t, json.Unmarshal(params, &p))
+			allowDuplicate = append(allowDuplicate, p.Note.Options.AllowDuplicate)
+			return int64(42)
+		}
+		return nil
+	})
+	client := NewClient(server.URL, "")
+	note := Note{Deck: "Japanese", NoteType: "Basic", Fields: map[string]string{"Front": "雨"}}
+
+	assert.ErrorIs(t, client.CanAddNote(note), ErrorDuplicate)
+
+	id, err := client.AddNote(note, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+	assert.Equal(t, []bool{true}, allowDuplicate)
+}
+
+func TestCanAddNoteGivesTheReason(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		var p struct {
+			Notes []struct {
+				Deck string `json:"deckName"`
+			} `json:"notes"`
+		}
+		require.NoError(t, json.Unmarshal(params, &p))
+		if p.Notes[0].Deck == "Japanese" {
+			return []any{map[string]any{"canAdd": true}}
+		}
+		return []any{map[string]any{"canAdd": false, "error": "deck was not found: " + p.Notes[0].Deck}}
+	})
+	client := NewClient(server.URL, "")
+
+	assert.NoError(t, client.CanAddNote(Note{Deck: "Japanese", NoteType: "Basic", Fields: map[string]string{"Front": "雨"}}))
+
+	err := client.CanAddNote(Note{Deck: "Mining", NoteType: "Basic", Fields: map[string]string{"Front": "雨"}})
+	assert.EqualError(t, err, "anki: deck was not found: Mining")
+	assert.NotErrorIs(t, err, ErrorDuplicate)
+}
+
+func TestAddNotesReportsRejectedNotes(t *testing.T) {
+	server := fakeAnki(t, "", func(action string, params json.RawMessage) any {
+		return []any{int64(1700000000001), nil}
+	})
+
+	ids, err := NewClient(server.URL, "").AddNotes([]Note{{Deck: "JLPT"}, {Deck: "JLPT"}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1700000000001, 0}, ids)
+}
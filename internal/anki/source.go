@@ -0,0 +1,73 @@
+package anki
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Source is what cards are reviewed from: decks, an Anki search, or both,
+// in which case the search is applied to the decks.
+type Source struct {
+	Decks []string
+	Query string
+	// Label is the short name shown in the status bar.
+	Label string
+}
+
+// NewSource returns the source of the decks and search, leaving out empty and
+// repeated deck names.
+func NewSource(decks []string, query, label string) Source {
+	var unique []string
+	for _, d := range decks {
+		if d != "" && !slices.Contains(unique, d) {
+			unique = append(unique, d)
+		}
+	}
+
+	return Source{
+		Decks: unique,
+		Query: strings.TrimSpace(query),
+		Label: label,
+	}
+}
+
+func (s Source) IsEmpty() bool {
+	return len(s.Decks) == 0 && s.Query == ""
+}
+
+// Search returns the Anki search matching the cards of the source.
+func (s Source) Search() string {
+	var parts []string
+
+	if len(s.Decks) > 0 {
+		decks := make([]string, len(s.Decks))
+		for i, d := range s.Decks {
+			decks[i] = fmt.Sprintf("deck:\"%s\"", d)
+		}
+		if len(decks) == 1 {
+			parts = append(parts, decks[0])
+		} else {
+			parts = append(parts, "("+strings.Join(decks, " OR ")+")")
+		}
+	}
+	if s.Query != "" {
+		parts = append(parts, "("+s.Query+")")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Name is the label, or the deck name when there is a single deck.
+func (s Source) Name() string {
+	switch {
+	case s.Label != "":
+		return s.Label
+	case len(s.Decks) == 1 && s.Query == "":
+		return s.Decks[0]
+	case len(s.Decks) == 0:
+		return s.Query
+	default:
+		return strings.Join(s.Decks, "+")
+	}
+}
@@ -0,0 +1,32 @@
+package anki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSource(t *testing.T) {
+	src := NewSource([]string{"Core2k", "Core2k", "Kanji"}, " tag:core ", "")
+
+	assert.Equal(t, []string{"Core2k", "Kanji"}, src.Decks)
+	assert.Equal(t, `(deck:"Core2k" OR deck:"Kanji") (tag:core)`, src.Search())
+	assert.Equal(t, "Core2k+Kanji", src.Name())
+	assert.False(t, src.IsEmpty())
+
+	assert.True(t, NewSource([]string{""}, "", "").IsEmpty())
+}
+
+func TestSourceName(t *testing.T) {
+	tests := []struct {
+		src  Source
+		want string
+	}{
+		{Source{Decks: []string{"Core2k"}}, "Core2k"},
+		{Source{Query: "deck:Japanese::* tag:core"}, "deck:Japanese::* tag:core"},
+		{Source{Query: "tag:core", Label: "Core"}, "Core"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.src.Name())
+	}
+}
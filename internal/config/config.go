@@ -5,23 +5,108 @@ import (
 	"os"
 	"sync"
 
+	"github.com/LealKevin/keiko/internal/anki"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 	"go.yaml.in/yaml/v3"
 )
 
+// Study modes for the built-in cards.
+const (
+	StudyVocab = "vocab"
+	StudyKanji = "kanji"
+	StudyBoth  = "both"
+)
+
 type UserConfig struct {
 	LoopInterval int   `mapstructure:"loop_interval" yaml:"loop_interval"`
 	JLPTLevel    []int `mapstructure:"jlpt_level" yaml:"jlpt_level"`
 
+	// Decks are imported word lists studied alongside the JLPT levels.
+	Decks []string `mapstructure:"decks" yaml:"decks"`
+
+	// Study is what the status bar quizzes outside Anki mode: "vocab",
+	// "kanji" or "both", which alternates between the two.
+	Study          string `mapstructure:"study" yaml:"study"`
+	NewWordsPerDay int    `mapstructure:"new_words_per_day" yaml:"new_words_per_day"`
+	NewKanjiPerDay int    `mapstructure:"new_kanji_per_day" yaml:"new_kanji_per_day"`
+	// OnLevelComplete is what happens once every word of a level was seen:
+	// "advance" to the next level, "reset" the level, or "stop".
+	OnLevelComplete string `mapstructure:"on_level_complete" yaml:"on_level_complete"`
+
 	IsFuriganaVisible    bool `mapstructure:"is_furigana_visible" yaml:"is_furigana_visible"`
 	IsJLPTLevelVisible   bool `mapstructure:"is_jlpt_level_visible" yaml:"is_jlpt_level_visible"`
 	IsTranslationVisible bool `mapstructure:"is_translation_visible" yaml:"is_translation_visible"`
+	IsProgressVisible    bool `mapstructure:"is_progress_visible" yaml:"is_progress_visible"`
+	// IsSentenceVisible adds an example sentence from the news corpus to
+	// revealed vocab answers.
+	IsSentenceVisible bool `mapstructure:"is_sentence_visible" yaml:"is_sentence_visible"`
 
-	AnkiDeck        string `mapstructure:"anki_deck" yaml:"anki_deck"`
-	AnkiModeEnabled bool   `mapstructure:"anki_mode_enabled" yaml:"anki_mode_enabled"`
+	AnkiDeck string `mapstructure:"anki_deck" yaml:"anki_deck"`
+	// AnkiDecks are reviewed together with AnkiDeck, and AnkiQuery is an
+	// Anki search narrowing them down, or the only source when no deck is
+	// set. AnkiLabel names the source in the status bar.
+	AnkiDecks       []string `mapstructure:"anki_decks" yaml:"anki_decks"`
+	AnkiQuery       string   `mapstructure:"anki_query" yaml:"anki_query"`
+	AnkiLabel       string   `mapstructure:"anki_label" yaml:"anki_label"`
+	AnkiModeEnabled bool     `mapstructure:"anki_mode_enabled" yaml:"anki_mode_enabled"`
+	// AnkiURL and AnkiAPIKey point to AnkiConnect, for Anki running on
+	// another machine or with an apiKey set.
+	AnkiURL    string `mapstructure:"anki_url" yaml:"anki_url"`
+	AnkiAPIKey string `mapstructure:"anki_api_key" yaml:"anki_api_key"`
+	// AnkiProfilePath is the Anki profile folder whose collection is read
+	// while AnkiConnect is not running.
+	AnkiProfilePath string `mapstructure:"anki_profile_path" yaml:"anki_profile_path"`
+	// AnkiSyncInterval is the minutes between syncs of the Anki collection,
+	// and AnkiSyncAfter the answered cards that trigger one. 0 disables
+	// either.
+	AnkiSyncInterval int `mapstructure:"anki_sync_interval" yaml:"anki_sync_interval"`
+	AnkiSyncAfter    int `mapstructure:"anki_sync_after" yaml:"anki_sync_after"`
+	// AnkiNoteTypes map note fields to roles per note type. Note types
+	// without an entry fall back to guessing from common field names.
+	AnkiNoteTypes []AnkiNoteType `mapstructure:"anki_note_types" yaml:"anki_note_types"`
+	// AnkiAddDeck and AnkiAddNoteType are where words from the news reader
+	// are added. An empty deck means AnkiDeck.
+	AnkiAddDeck     string `mapstructure:"anki_add_deck" yaml:"anki_add_deck"`
+	AnkiAddNoteType string `mapstructure:"anki_add_note_type" yaml:"anki_add_note_type"`
 
 	NewsServerURL string `mapstructure:"news_server_url" yaml:"news_server_url"`
+
+	// Display is where the status bar is shown: "tmux", "stdout", "file",
+	// "zellij" or "screen". DisplayFile is the file written by "file",
+	// empty for status.txt next to the config.
+	Display     string `mapstructure:"display" yaml:"display"`
+	DisplayFile string `mapstructure:"display_file" yaml:"display_file"`
+
+	Keys KeyBindings `mapstructure:"keys" yaml:"keys"`
+
+	Theme Theme `mapstructure:"theme" yaml:"theme"`
+	// Formats are text/template status lines by mode ("vocab", "kanji",
+	// "anki") and state ("question", "answer", "done", ...), replacing the
+	// built-in layout.
+	Formats map[string]map[string]string `mapstructure:"formats" yaml:"formats,omitempty"`
+}
+
+// KeyBindings are the global hotkeys, by key name such as "F5".
+type KeyBindings struct {
+	Settings   string `mapstructure:"settings" yaml:"settings"`
+	ToggleMode string `mapstructure:"toggle_mode" yaml:"toggle_mode"`
+	Reveal     string `mapstructure:"reveal" yaml:"reveal"`
+	Again      string `mapstructure:"again" yaml:"again"`
+	Hard       string `mapstructure:"hard" yaml:"hard"`
+	Good       string `mapstructure:"good" yaml:"good"`
+	Easy       string `mapstructure:"easy" yaml:"easy"`
+}
+
+// AnkiNoteType names the fields of a note type shown as question, reading
+// and answer. Sentence gets the example sentence of words added from the
+// news reader.
+type AnkiNoteType struct {
+	Name     string `mapstructure:"name" yaml:"name"`
+	Question string `mapstructure:"question" yaml:"question"`
+	Reading  string `mapstructure:"reading" yaml:"reading"`
+	Answer   string `mapstructure:"answer" yaml:"answer"`
+	Sentence string `mapstructure:"sentence" yaml:"sentence,omitempty"`
 }
 
 type Config struct {
@@ -50,12 +135,40 @@ func (c *Config) Init() error {
 
 	c.Viper.SetDefault("loop_interval", 10)
 	c.Viper.SetDefault("jlpt_level", []int{1, 2, 3, 4, 5})
+	c.Viper.SetDefault("decks", []string{})
+	c.Viper.SetDefault("study", StudyVocab)
+	c.Viper.SetDefault("new_words_per_day", 20)
+	c.Viper.SetDefault("new_kanji_per_day", 5)
+	c.Viper.SetDefault("on_level_complete", "advance")
 	c.Viper.SetDefault("is_furigana_visible", true)
 	c.Viper.SetDefault("is_jlpt_level_visible", true)
 	c.Viper.SetDefault("is_translation_visible", true)
+	c.Viper.SetDefault("is_progress_visible", false)
+	c.Viper.SetDefault("is_sentence_visible", false)
 	c.Viper.SetDefault("anki_deck", "")
+	c.Viper.SetDefault("anki_decks", []string{})
+	c.Viper.SetDefault("anki_query", "")
+	c.Viper.SetDefault("anki_label", "")
 	c.Viper.SetDefault("anki_mode_enabled", false)
+	c.Viper.SetDefault("anki_url", "http://localhost:8765")
+	c.Viper.SetDefault("anki_api_key", "")
+	c.Viper.SetDefault("anki_profile_path", "")
+	c.Viper.SetDefault("anki_sync_interval", 0)
+	c.Viper.SetDefault("anki_sync_after", 0)
+	c.Viper.SetDefault("anki_note_types", []AnkiNoteType{})
+	c.Viper.SetDefault("anki_add_deck", "")
+	c.Viper.SetDefault("anki_add_note_type", "Basic")
 	c.Viper.SetDefault("news_server_url", "http://localhost:8080")
+	c.Viper.SetDefault("display", "tmux")
+	c.Viper.SetDefault("display_file", "")
+	c.Viper.SetDefault("keys.settings", "F2")
+	c.Viper.SetDefault("keys.toggle_mode", "F3")
+	c.Viper.SetDefault("keys.reveal", "F4")
+	c.Viper.SetDefault("keys.again", "F5")
+	c.Viper.SetDefault("keys.good", "F6")
+	c.Viper.SetDefault("keys.hard", "F7")
+	c.Viper.SetDefault("keys.easy", "F8")
+	c.Viper.SetDefault("theme.preset", DefaultTheme)
 
 	err := c.Viper.ReadInConfig()
 	if err != nil {
@@ -138,3 +251,87 @@ func (c *Config) ToggleTranslation() {
 	c.mu.Unlock()
 	c.Save()
 }
+
+func (c *Config) ToggleProgress() {
+	c.mu.Lock()
+	c.UserConfig.IsProgressVisible = !c.UserConfig.IsProgressVisible
+	c.mu.Unlock()
+	c.Save()
+}
+
+func (c *Config) ToggleSentence() {
+	c.mu.Lock()
+	c.UserConfig.IsSentenceVisible = !c.UserConfig.IsSentenceVisible
+	c.mu.Unlock()
+	c.Save()
+}
+
+// AnkiAddTarget returns the deck and note type words from the news reader
+// are added to.
+func (u UserConfig) AnkiAddTarget() (deck, noteType string) {
+	deck = u.AnkiAddDeck
+	if deck == "" {
+		deck = u.AnkiDeck
+	}
+	return deck, u.AnkiAddNoteType
+}
+
+// AnkiClient returns a client for the AnkiConnect endpoint, reading cards
+// with the fields set for their note type.
+func (u UserConfig) AnkiClient() *anki.Client {
+	client := anki.NewClient(u.AnkiURL, u.AnkiAPIKey)
+	for _, nt := range u.AnkiNoteTypes {
+		client.SetFieldMapping(nt.Name, anki.FieldMapping{
+			Question: nt.Question,
+			Reading:  nt.Reading,
+			Answer:   nt.Answer,
+			Sentence: nt.Sentence,
+		})
+	}
+	return client
+}
+
+// AnkiBackend returns AnkiClient, falling back to the collection of
+// anki_profile_path while Anki is closed.
+func (u UserConfig) AnkiBackend() anki.Backend {
+	return anki.NewBackend(u.AnkiClient(), u.AnkiProfilePath)
+}
+
+// AnkiSource returns what Anki cards are reviewed from.
+func (u UserConfig) AnkiSource() anki.Source {
+	return anki.NewSource(append([]string{u.AnkiDeck}, u.AnkiDecks...), u.AnkiQuery, u.AnkiLabel)
+}
+
+// Format returns the status line template of the mode and state, or "" for
+// the built-in layout.
+func (u UserConfig) Format(mode, state string) string {
+	return u.Formats[mode][state]
+}
+
+// AnkiNoteType returns the field mapping of the named note type.
+func (u UserConfig) AnkiNoteType(name string) (AnkiNoteType, bool) {
+	for _, nt := range u.AnkiNoteTypes {
+		if nt.Name == name {
+			return nt, true
+		}
+	}
+	return AnkiNoteType{Name: name}, false
+}
+
+// SetAnkiNoteType adds or replaces the field mapping of a note type.
+func (c *Config) SetAnkiNoteType(noteType AnkiNoteType) {
+	c.mu.Lock()
+	replaced := false
+	for i, nt := range c.UserConfig.AnkiNoteTypes {
+		if nt.Name == noteType.Name {
+			c.UserConfig.AnkiNoteTypes[i] = noteType
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.UserConfig.AnkiNoteTypes = append(c.UserConfig.AnkiNoteTypes, noteType)
+	}
+	c.mu.Unlock()
+	c.Save()
+}
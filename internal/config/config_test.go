@@ -39,9 +39,38 @@ func TestInit(t *testing.T) {
 
 		assert.Equal(t, 10, cfg.UserConfig.LoopInterval)
 		assert.Equal(t, []int{1, 2, 3, 4, 5}, cfg.UserConfig.JLPTLevel)
+		assert.Equal(t, 20, cfg.UserConfig.NewWordsPerDay)
+		assert.Equal(t, StudyVocab, cfg.UserConfig.Study)
+		assert.Equal(t, 5, cfg.UserConfig.NewKanjiPerDay)
+		assert.Equal(t, "http://localhost:8765", cfg.UserConfig.AnkiURL)
+		assert.Empty(t, cfg.UserConfig.AnkiAPIKey)
+		assert.Zero(t, cfg.UserConfig.AnkiSyncInterval)
+		assert.Zero(t, cfg.UserConfig.AnkiSyncAfter)
+		assert.Equal(t, "tmux", cfg.UserConfig.Display)
+		assert.Equal(t, Theme{Preset: DefaultTheme}, cfg.UserConfig.Theme)
+		assert.Equal(t, "advance", cfg.UserConfig.OnLevelComplete)
 		assert.True(t, cfg.UserConfig.IsFuriganaVisible)
 		assert.True(t, cfg.UserConfig.IsJLPTLevelVisible)
 		assert.True(t, cfg.UserConfig.IsTranslationVisible)
+		assert.Equal(t, KeyBindings{
+			Settings: "F2", ToggleMode: "F3", Reveal: "F4",
+			Again: "F5", Good: "F6", Hard: "F7", Easy: "F8",
+		}, cfg.UserConfig.Keys)
+	})
+
+	t.Run("overrides single keys", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := `keys:
+  again: F9
+`
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o644))
+
+		cfg, err := New(configPath)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Init())
+
+		assert.Equal(t, "F9", cfg.UserConfig.Keys.Again)
+		assert.Equal(t, "F6", cfg.UserConfig.Keys.Good)
 	})
 
 	t.Run("loads existing config", func(t *testing.T) {
@@ -104,6 +133,30 @@ func TestToggleTranslation(t *testing.T) {
 	assert.True(t, cfg.UserConfig.IsTranslationVisible)
 }
 
+func TestToggleProgress(t *testing.T) {
+	cfg, _ := setupTestConfig(t)
+
+	assert.False(t, cfg.UserConfig.IsProgressVisible)
+
+	cfg.ToggleProgress()
+	assert.True(t, cfg.UserConfig.IsProgressVisible)
+
+	cfg.ToggleProgress()
+	assert.False(t, cfg.UserConfig.IsProgressVisible)
+}
+
+func TestToggleSentence(t *testing.T) {
+	cfg, _ := setupTestConfig(t)
+
+	assert.False(t, cfg.UserConfig.IsSentenceVisible)
+
+	cfg.ToggleSentence()
+	assert.True(t, cfg.UserConfig.IsSentenceVisible)
+
+	cfg.ToggleSentence()
+	assert.False(t, cfg.UserConfig.IsSentenceVisible)
+}
+
 func TestIncreaseInterval(t *testing.T) {
 	cfg, _ := setupTestConfig(t)
 
@@ -163,3 +216,50 @@ func TestSave(t *testing.T) {
 	assert.Contains(t, string(content), "loop_interval: 120")
 	assert.Contains(t, string(content), "is_furigana_visible: false")
 }
+
+func TestSetAnkiNoteType(t *testing.T) {
+	cfg, configPath := setupTestConfig(t)
+
+	_, ok := cfg.UserConfig.AnkiNoteType("Core 2k/6k v3.1")
+	assert.False(t, ok)
+
+	cfg.SetAnkiNoteType(AnkiNoteType{Name: "Core 2k/6k v3.1", Question: "Vocabulary-Kanji", Answer: "Vocabulary-English"})
+	cfg.SetAnkiNoteType(AnkiNoteType{Name: "Core 2k/6k v3.1", Question: "Vocabulary-Kanji", Reading: "Vocabulary-Kana", Answer: "Vocabulary-English"})
+
+	require.Len(t, cfg.UserConfig.AnkiNoteTypes, 1)
+
+	// Note type names keep their case and dots after a reload.
+	reloaded, err := New(configPath)
+	require.NoError(t, err)
+	require.NoError(t, reloaded.Init())
+
+	nt, ok := reloaded.UserConfig.AnkiNoteType("Core 2k/6k v3.1")
+	require.True(t, ok)
+	assert.Equal(t, "Vocabulary-Kana", nt.Reading)
+}
+
+func TestAnkiSource(t *testing.T) {
+	src := UserConfig{
+		AnkiDeck:  "Core2k",
+		AnkiDecks: []string{"Core2k", "Kanji"},
+		AnkiQuery: " tag:core ",
+		AnkiLabel: "core",
+	}.AnkiSource()
+
+	assert.Equal(t, []string{"Core2k", "Kanji"}, src.Decks)
+	assert.Equal(t, "tag:core", src.Query)
+	assert.Equal(t, "core", src.Name())
+	assert.True(t, UserConfig{}.AnkiSource().IsEmpty())
+}
+
+func TestThemeResolve(t *testing.T) {
+	theme := Theme{Preset: "nord", Word: "#ffffff"}.Resolve()
+	assert.Equal(t, "#ffffff", theme.Word)
+	assert.Equal(t, ThemePresets["nord"].Meaning, theme.Meaning)
+	assert.Equal(t, "nord", theme.Preset)
+
+	// Unknown presets fall back to the default one.
+	theme = Theme{Preset: "neon", Done: "green"}.Resolve()
+	assert.Equal(t, ThemePresets[DefaultTheme].Background, theme.Background)
+	assert.Equal(t, "green", theme.Done)
+}
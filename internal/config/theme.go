@@ -0,0 +1,113 @@
+package config
+
+// DefaultTheme is the preset used when the theme names none, or an unknown
+// one.
+const DefaultTheme = "default"
+
+// Theme colours the status bar, as "#rrggbb" or colour names the display
+// knows. Preset picks a built-in theme whose colours the other fields
+// override. Empty colours use the line colour of the state, and an empty
+// state colour or background leaves the display's own.
+type Theme struct {
+	Preset     string `mapstructure:"preset" yaml:"preset"`
+	Background string `mapstructure:"background" yaml:"background,omitempty"`
+
+	// Line colours per review state. Disconnected is also used when no deck
+	// is selected or the API key is rejected.
+	Question     string `mapstructure:"question" yaml:"question,omitempty"`
+	Answer       string `mapstructure:"answer" yaml:"answer,omitempty"`
+	Disconnected string `mapstructure:"disconnected" yaml:"disconnected,omitempty"`
+	Done         string `mapstructure:"done" yaml:"done,omitempty"`
+
+	// Element colours.
+	Word     string `mapstructure:"word" yaml:"word,omitempty"`
+	Furigana string `mapstructure:"furigana" yaml:"furigana,omitempty"`
+	Meaning  string `mapstructure:"meaning" yaml:"meaning,omitempty"`
+	Level    string `mapstructure:"level" yaml:"level,omitempty"`
+	Prefix   string `mapstructure:"prefix" yaml:"prefix,omitempty"`
+	Hint     string `mapstructure:"hint" yaml:"hint,omitempty"`
+}
+
+// ThemePresets are the built-in themes.
+var ThemePresets = map[string]Theme{
+	DefaultTheme: {
+		Background:   "#1a1a2e",
+		Question:     "#e94560",
+		Answer:       "#e94560",
+		Disconnected: "#e94560",
+		Done:         "#e94560",
+	},
+	// plain keeps the colours of the terminal or bar.
+	"plain": {},
+	"nord": {
+		Background:   "#2e3440",
+		Question:     "#d8dee9",
+		Answer:       "#eceff4",
+		Disconnected: "#bf616a",
+		Done:         "#a3be8c",
+		Word:         "#88c0d0",
+		Furigana:     "#81a1c1",
+		Meaning:      "#ebcb8b",
+		Level:        "#4c566a",
+		Prefix:       "#5e81ac",
+		Hint:         "#4c566a",
+	},
+	"gruvbox": {
+		Background:   "#282828",
+		Question:     "#ebdbb2",
+		Answer:       "#ebdbb2",
+		Disconnected: "#fb4934",
+		Done:         "#b8bb26",
+		Word:         "#fabd2f",
+		Furigana:     "#83a598",
+		Meaning:      "#8ec07c",
+		Level:        "#928374",
+		Prefix:       "#d3869b",
+		Hint:         "#928374",
+	},
+	"solarized": {
+		Background:   "#002b36",
+		Question:     "#93a1a1",
+		Answer:       "#93a1a1",
+		Disconnected: "#dc322f",
+		Done:         "#859900",
+		Word:         "#b58900",
+		Furigana:     "#2aa198",
+		Meaning:      "#268bd2",
+		Level:        "#586e75",
+		Prefix:       "#6c71c4",
+		Hint:         "#586e75",
+	},
+}
+
+// Resolve returns the preset with the colours set in t on top.
+func (t Theme) Resolve() Theme {
+	preset, ok := ThemePresets[t.Preset]
+	if !ok {
+		preset = ThemePresets[DefaultTheme]
+	}
+
+	resolved := preset
+	resolved.Preset = t.Preset
+	for _, c := range []struct {
+		dst *string
+		src string
+	}{
+		{&resolved.Background, t.Background},
+		{&resolved.Question, t.Question},
+		{&resolved.Answer, t.Answer},
+		{&resolved.Disconnected, t.Disconnected},
+		{&resolved.Done, t.Done},
+		{&resolved.Word, t.Word},
+		{&resolved.Furigana, t.Furigana},
+		{&resolved.Meaning, t.Meaning},
+		{&resolved.Level, t.Level},
+		{&resolved.Prefix, t.Prefix},
+		{&resolved.Hint, t.Hint},
+	} {
+		if c.src != "" {
+			*c.dst = c.src
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,179 @@
+// Package ctl lets `keiko ctl` drive the running status bar over a Unix
+// socket, for setups where global hotkeys do not work.
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/srs"
+	"github.com/LealKevin/keiko/internal/ui"
+)
+
+// Commands understood by the daemon.
+const (
+	CommandReveal = "reveal"
+	CommandAgain  = "again"
+	CommandHard   = "hard"
+	CommandGood   = "good"
+	CommandEasy   = "easy"
+	CommandNext   = "next"
+	CommandToggle = "toggle"
+	CommandPause  = "pause"
+	CommandStatus = "status"
+)
+
+// Commands lists the commands in usage order.
+var Commands = []string{CommandReveal, CommandAgain, CommandHard, CommandGood, CommandEasy, CommandNext, CommandToggle, CommandPause, CommandStatus}
+
+var (
+	// ErrorRunning means another daemon already listens on the socket.
+	ErrorRunning = errors.New("keiko is already running")
+	// ErrorNotRunning means no daemon listens on the socket.
+	ErrorNotRunning = errors.New("keiko is not running")
+)
+
+const (
+	replyOK    = "ok"
+	replyError = "error: "
+	timeout    = 5 * time.Second
+)
+
+// Controller is what the commands act on.
+type Controller interface {
+	RevealAnswer()
+	AnswerCard(ease int)
+	Skip()
+	ToggleMode()
+	TogglePause() bool
+	Status() ui.Status
+}
+
+// SocketPath is where the daemon listens: in XDG_RUNTIME_DIR, or the
+// temporary directory with the user ID in the name.
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "keiko.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("keiko-%d.sock", os.Getuid()))
+}
+
+type Server struct {
+	path     string
+	listener net.Listener
+	c        Controller
+}
+
+// Listen opens the socket. A socket left by a daemon that did not exit
+// cleanly is replaced.
+func Listen(path string, c Controller) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, ErrorRunning
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error removing stale socket: %s", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening control socket: %s", err)
+	}
+	return &Server{path: path, listener: listener, c: c}, nil
+}
+
+// Serve answers commands, one per connection, until the server is closed.
+func (s *Server) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("control socket failed: %v", err)
+			}
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	reply, err := s.handle(strings.TrimSpace(command))
+	if err != nil {
+		reply = replyError + err.Error()
+	}
+	fmt.Fprintln(conn, reply)
+}
+
+func (s *Server) handle(command string) (string, error) {
+	switch command {
+	case CommandReveal:
+		s.c.RevealAnswer()
+	case CommandAgain:
+		s.c.AnswerCard(int(srs.Again))
+	case CommandHard:
+		s.c.AnswerCard(int(srs.Hard))
+	case CommandGood:
+		s.c.AnswerCard(int(srs.Good))
+	case CommandEasy:
+		s.c.AnswerCard(int(srs.Easy))
+	case CommandNext:
+		s.c.Skip()
+	case CommandToggle:
+		s.c.ToggleMode()
+	case CommandPause:
+		if s.c.TogglePause() {
+			return "paused", nil
+		}
+		return "resumed", nil
+	case CommandStatus:
+		status, err := json.Marshal(s.c.Status())
+		return string(status), err
+	default:
+		return "", fmt.Errorf("unknown command %q", command)
+	}
+	return replyOK, nil
+}
+
+// Close stops serving and removes the socket.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Send sends the command to the daemon and returns its reply.
+func Send(path, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrorNotRunning, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", fmt.Errorf("error sending command: %s", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("error reading reply: %s", err)
+	}
+
+	reply = strings.TrimSpace(reply)
+	if msg, ok := strings.CutPrefix(reply, replyError); ok {
+		return "", errors.New(msg)
+	}
+	return reply, nil
+}
@@ -0,0 +1,80 @@
+package ctl
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/LealKevin/keiko/internal/srs"
+	"github.com/LealKevin/keiko/internal/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeController struct {
+	revealed bool
+	answers  []int
+	skipped  int
+	toggled  int
+	paused   bool
+}
+
+func (f *fakeController) RevealAnswer()       { f.revealed = true }
+func (f *fakeController) AnswerCard(ease int) { f.answers = append(f.answers, ease) }
+func (f *fakeController) Skip()               { f.skipped++ }
+func (f *fakeController) ToggleMode()         { f.toggled++ }
+
+func (f *fakeController) TogglePause() bool {
+	f.paused = !f.paused
+	return f.paused
+}
+
+func (f *fakeController) Status() ui.Status {
+	return ui.Status{Mode: "anki", State: "question", Text: "猫", Due: 3, Paused: f.paused}
+}
+
+func listen(t *testing.T) (string, *fakeController) {
+	path := filepath.Join(t.TempDir(), "keiko.sock")
+	c := &fakeController{}
+	server, err := Listen(path, c)
+	require.NoError(t, err)
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+	return path, c
+}
+
+func TestSend(t *testing.T) {
+	path, c := listen(t)
+
+	for _, command := range []string{CommandReveal, CommandGood, CommandAgain, CommandNext, CommandToggle} {
+		reply, err := Send(path, command)
+		require.NoError(t, err, command)
+		assert.Equal(t, "ok", reply)
+	}
+	assert.True(t, c.revealed)
+	assert.Equal(t, []int{int(srs.Good), int(srs.Again)}, c.answers)
+	assert.Equal(t, 1, c.skipped)
+	assert.Equal(t, 1, c.toggled)
+
+	reply, err := Send(path, CommandPause)
+	require.NoError(t, err)
+	assert.Equal(t, "paused", reply)
+
+	reply, err = Send(path, CommandStatus)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"mode":"anki","state":"question","text":"猫","due":3,"paused":true}`, reply)
+
+	_, err = Send(path, "jump")
+	assert.EqualError(t, err, `unknown command "jump"`)
+}
+
+func TestListenRunning(t *testing.T) {
+	path, _ := listen(t)
+
+	_, err := Listen(path, &fakeController{})
+	assert.ErrorIs(t, err, ErrorRunning)
+}
+
+func TestSendNotRunning(t *testing.T) {
+	_, err := Send(filepath.Join(t.TempDir(), "keiko.sock"), CommandGood)
+	assert.ErrorIs(t, err, ErrorNotRunning)
+}
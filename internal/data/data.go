@@ -1,10 +1,13 @@
 package data
 
 import (
+	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type Word struct {
@@ -14,6 +17,17 @@ type Word struct {
 	Furigana string `json:"furigana"`
 	Romaji   string `json:"romaji"`
 	Level    int    `json:"level"`
+	Deck     string `json:"deck,omitempty"`
+}
+
+// CustomLevel is the level of words imported into user decks, which have no
+// JLPT level.
+const CustomLevel = 0
+
+type Deck struct {
+	ID        int
+	Name      string
+	WordCount int
 }
 
 var (
@@ -21,6 +35,43 @@ var (
 	ErrorParsingWords  = errors.New("error parsing words")
 )
 
+// jlptWords is the JLPT N5-N1 vocabulary shipped with the binary, in the same
+// format as the remote word API.
+//
+//go:embed jlpt_words.json
+var jlptWords []byte
+
+// BundledWords returns the embedded JLPT vocabulary, so a first run works
+// offline.
+func BundledWords() ([]Word, error) {
+	words := []Word{}
+	if err := json.Unmarshal(jlptWords, &words); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorParsingWords, err)
+	}
+	return cleanWords(words), nil
+}
+
+// cleanWords drops the words without a meaning to reveal, and clears the
+// notes like （感） the word API has in place of some readings.
+func cleanWords(words []Word) []Word {
+	clean := words[:0]
+	for _, w := range words {
+		meaning := strings.TrimSpace(w.Meaning)
+		if meaning == "" || strings.HasPrefix(meaning, "TODO") {
+			continue
+		}
+		if strings.HasPrefix(w.Furigana, "（") {
+			w.Furigana = ""
+		}
+		if strings.HasPrefix(w.Romaji, "(") {
+			w.Romaji = ""
+		}
+		clean = append(clean, w)
+	}
+	return clean
+}
+
+// FetchWords downloads the latest vocabulary from the remote word API.
 func FetchWords() ([]Word, error) {
 	response, err := http.Get("https://jlpt-vocab-api.vercel.app/api/words/all")
 	if err != nil {
@@ -28,11 +79,83 @@ func FetchWords() ([]Word, error) {
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code: %d", ErrorFetchingWords, response.StatusCode)
+	}
+
 	words := []Word{}
 	err = json.NewDecoder(response.Body).Decode(&words)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrorParsingWords, err)
 	}
 
-	return words, nil
+	return cleanWords(words), nil
+}
+
+const (
+	SourceVocab = "vocab"
+	SourceAnki  = "anki"
+)
+
+// Review is one answered card, either from the built-in vocabulary or Anki.
+type Review struct {
+	Source     string
+	CardID     int64
+	Grade      int
+	ReviewedAt time.Time
+	Duration   time.Duration
+}
+
+type DailyReviews struct {
+	Day     string // YYYY-MM-DD in local time
+	Reviews int
+	Passed  int
+}
+
+type LevelProgress struct {
+	Level   int
+	Total   int
+	Seen    int
+	Due     int // introduced words due for review now
+	Learned int // words with an interval of at least LearnedInterval days
+}
+
+// WordProgress is a word with how far it was studied.
+type WordProgress struct {
+	Word
+	Seen     bool
+	Interval int // review interval in days, 0 when never reviewed
+}
+
+// LearnedInterval is the review interval in days after which a word counts as
+// learned, like a mature card in Anki.
+const LearnedInterval = 21
+
+// Sentence is an example of a word taken from an NHK Easy article.
+type Sentence struct {
+	Word  string
+	NhkID string
+	Title string
+	Text  string
+}
+
+// AnkiCard is a due Anki card kept for reviewing while Anki is closed.
+type AnkiCard struct {
+	CardID int64
+	// Source is the search the card was due for, Deck the deck it is in.
+	Source   string
+	Deck     string
+	Question string
+	Reading  string
+	Answer   string
+	Mod      int64 // card modification time in Anki when cached
+}
+
+// PendingAnkiAnswer is an answer given offline, waiting to be sent to Anki.
+type PendingAnkiAnswer struct {
+	ID         int64
+	CardID     int64
+	Ease       int
+	Mod        int64
+	AnsweredAt time.Time
 }
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundledWords(t *testing.T) {
+	words, err := BundledWords()
+	require.NoError(t, err)
+
+	assert.Greater(t, len(words), 5000)
+
+	levels := map[int]int{}
+	for _, w := range words {
+		levels[w.Level]++
+		assert.NotEmpty(t, w.Word)
+		assert.NotEmpty(t, w.Meaning, w.Word)
+		assert.NotContains(t, w.Meaning, "TODO", w.Word)
+		assert.NotContains(t, w.Furigana, "（", w.Word)
+		assert.NotContains(t, w.Romaji, "(", w.Word)
+	}
+	for level := 1; level <= 5; level++ {
+		assert.NotZero(t, levels[level], "missing words for N%d", level)
+	}
+}
+
+func TestCleanWords(t *testing.T) {
+	words := cleanWords([]Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Romaji: "inu", Level: 5},
+		{Word: "だが", Meaning: " ", Furigana: "だが", Romaji: "daga", Level: 3},
+		{Word: "いえ", Meaning: "TODO same as いいえ?", Furigana: "いえ", Romaji: "ie", Level: 3},
+		{Word: "ふと", Meaning: "suddenly", Furigana: "（副）", Romaji: "()", Level: 3},
+	})
+
+	assert.Equal(t, []Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Romaji: "inu", Level: 5},
+		{Word: "ふと", Meaning: "suddenly", Level: 3},
+	}, words)
+}
+
+func TestBundledKanji(t *testing.T) {
+	kanji, err := BundledKanji()
+	require.NoError(t, err)
+
+	assert.Len(t, kanji, 2136)
+
+	characters := map[string]bool{}
+	levels := map[int]int{}
+	for _, k := range kanji {
+		assert.NotEmpty(t, k.Character)
+		assert.NotEmpty(t, k.Meanings, k.Character)
+		assert.Positive(t, k.Strokes, k.Character)
+		assert.True(t, k.Grade >= 1 && k.Grade <= 7, "bad grade for %s", k.Character)
+		assert.False(t, characters[k.Character], "duplicate kanji %s", k.Character)
+		characters[k.Character] = true
+		levels[k.Level]++
+	}
+	for level := 1; level <= 5; level++ {
+		assert.NotZero(t, levels[level], "missing kanji for N%d", level)
+	}
+}
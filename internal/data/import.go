@@ -0,0 +1,197 @@
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrorParsingDeck = errors.New("error parsing deck")
+
+// ColumnMapping tells the importer where to find each field. A column is
+// either a header name (case-insensitive) or a 1-based column index. Reading
+// and meaning are optional and may be left empty; their default columns are
+// skipped when the file does not have them.
+type ColumnMapping struct {
+	Word    string
+	Reading string
+	Meaning string
+}
+
+func DefaultColumnMapping() ColumnMapping {
+	return ColumnMapping{Word: "word", Reading: "reading", Meaning: "meaning"}
+}
+
+// DeckFormat guesses the file format from its extension.
+func DeckFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".json":
+		return "json"
+	default:
+		return "csv"
+	}
+}
+
+// ParseDeck reads words from a CSV, TSV or JSON file. CSV and TSV files must
+// start with a header row unless every column is given by index and
+// hasHeader is false. JSON files hold an array of objects keyed by name.
+func ParseDeck(r io.Reader, format string, mapping ColumnMapping, hasHeader bool) ([]Word, error) {
+	switch format {
+	case "csv":
+		return parseDelimited(r, ',', mapping, hasHeader)
+	case "tsv":
+		return parseDelimited(r, '\t', mapping, hasHeader)
+	case "json":
+		return parseJSON(r, mapping)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrorParsingDeck, format)
+	}
+}
+
+func parseDelimited(r io.Reader, delimiter rune, mapping ColumnMapping, hasHeader bool) ([]Word, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorParsingDeck, err)
+	}
+
+	var header []string
+	if hasHeader && len(records) > 0 {
+		header = records[0]
+		records = records[1:]
+	}
+
+	wordCol, err := columnIndex(header, mapping.Word)
+	if err != nil {
+		return nil, err
+	}
+	if wordCol < 0 {
+		return nil, fmt.Errorf("%w: word column is required", ErrorParsingDeck)
+	}
+	defaults := DefaultColumnMapping()
+	readingCol, err := optionalColumnIndex(header, mapping.Reading, defaults.Reading)
+	if err != nil {
+		return nil, err
+	}
+	meaningCol, err := optionalColumnIndex(header, mapping.Meaning, defaults.Meaning)
+	if err != nil {
+		return nil, err
+	}
+
+	var words []Word
+	for _, record := range records {
+		word := Word{
+			Word:     cell(record, wordCol),
+			Furigana: cell(record, readingCol),
+			Meaning:  cell(record, meaningCol),
+		}
+		if word.Word == "" {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return words, nil
+}
+
+// columnIndex resolves a mapping entry to a 0-based index, or -1 when the
+// column is not mapped.
+func columnIndex(header []string, column string) (int, error) {
+	if column == "" {
+		return -1, nil
+	}
+
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return -1, fmt.Errorf("%w: column index must start at 1, got %d", ErrorParsingDeck, n)
+		}
+		return n - 1, nil
+	}
+
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+
+	if header == nil {
+		return -1, fmt.Errorf("%w: column %q needs a header row", ErrorParsingDeck, column)
+	}
+	return -1, fmt.Errorf("%w: column %q not found in header", ErrorParsingDeck, column)
+}
+
+// optionalColumnIndex is columnIndex for an optional field, which is not
+// mapped when its default column is missing.
+func optionalColumnIndex(header []string, column, byDefault string) (int, error) {
+	i, err := columnIndex(header, column)
+	if err != nil && column == byDefault {
+		return -1, nil
+	}
+	return i, err
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseJSON(r io.Reader, mapping ColumnMapping) ([]Word, error) {
+	var entries []map[string]any
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorParsingDeck, err)
+	}
+
+	var words []Word
+	for _, entry := range entries {
+		word := Word{
+			Word:     jsonField(entry, mapping.Word),
+			Furigana: jsonField(entry, mapping.Reading),
+			Meaning:  jsonField(entry, mapping.Meaning),
+		}
+		if word.Word == "" {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return words, nil
+}
+
+func jsonField(entry map[string]any, key string) string {
+	if key == "" {
+		return ""
+	}
+	for k, v := range entry {
+		if !strings.EqualFold(k, key) {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			return strings.TrimSpace(v)
+		case []any:
+			parts := make([]string, 0, len(v))
+			for _, p := range v {
+				parts = append(parts, fmt.Sprint(p))
+			}
+			return strings.Join(parts, ", ")
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeckFormat(t *testing.T) {
+	assert.Equal(t, "csv", DeckFormat("words.csv"))
+	assert.Equal(t, "tsv", DeckFormat("words.TSV"))
+	assert.Equal(t, "json", DeckFormat("/tmp/words.json"))
+	assert.Equal(t, "csv", DeckFormat("words.txt"))
+}
+
+func TestParseDeckCSV(t *testing.T) {
+	t.Run("uses default header names", func(t *testing.T) {
+		input := "Word,Reading,Meaning\n関数,かんすう,function\n変数,へんすう,\"variable, var\"\n"
+
+		words, err := ParseDeck(strings.NewReader(input), "csv", DefaultColumnMapping(), true)
+
+		require.NoError(t, err)
+		assert.Equal(t, []Word{
+			{Word: "関数", Furigana: "かんすう", Meaning: "function"},
+			{Word: "変数", Furigana: "へんすう", Meaning: "variable, var"},
+		}, words)
+	})
+
+	t.Run("maps custom header names", func(t *testing.T) {
+		input := "english,kanji,kana\ndeploy,展開,てんかい\n"
+		mapping := ColumnMapping{Word: "kanji", Reading: "kana", Meaning: "english"}
+
+		words, err := ParseDeck(strings.NewReader(input), "csv", mapping, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, []Word{{Word: "展開", Furigana: "てんかい", Meaning: "deploy"}}, words)
+	})
+
+	t.Run("maps column indexes without header", func(t *testing.T) {
+		input := "展開\tdeploy\n\tskipped\n"
+		mapping := ColumnMapping{Word: "1", Meaning: "2"}
+
+		words, err := ParseDeck(strings.NewReader(input), "tsv", mapping, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, []Word{{Word: "展開", Meaning: "deploy"}}, words)
+	})
+
+	t.Run("skips missing default optional columns", func(t *testing.T) {
+		input := "word,meaning\n展開,deploy\n"
+
+		words, err := ParseDeck(strings.NewReader(input), "csv", DefaultColumnMapping(), true)
+
+		require.NoError(t, err)
+		assert.Equal(t, []Word{{Word: "展開", Meaning: "deploy"}}, words)
+	})
+
+	t.Run("fails on missing custom reading column", func(t *testing.T) {
+		input := "word,meaning\n展開,deploy\n"
+		mapping := DefaultColumnMapping()
+		mapping.Reading = "kana"
+
+		_, err := ParseDeck(strings.NewReader(input), "csv", mapping, true)
+
+		assert.ErrorIs(t, err, ErrorParsingDeck)
+		assert.Contains(t, err.Error(), `"kana"`)
+	})
+
+	t.Run("fails on unknown column", func(t *testing.T) {
+		input := "front,back\n展開,deploy\n"
+
+		_, err := ParseDeck(strings.NewReader(input), "csv", DefaultColumnMapping(), true)
+
+		assert.ErrorIs(t, err, ErrorParsingDeck)
+		assert.Contains(t, err.Error(), `"word"`)
+	})
+}
+
+func TestParseDeckJSON(t *testing.T) {
+	input := `[
+		{"term": "会議", "kana": "かいぎ", "meanings": ["meeting", "conference"]},
+		{"term": "", "kana": "skipped"}
+	]`
+	mapping := ColumnMapping{Word: "term", Reading: "kana", Meaning: "meanings"}
+
+	words, err := ParseDeck(strings.NewReader(input), "json", mapping, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Word{{Word: "会議", Furigana: "かいぎ", Meaning: "meeting, conference"}}, words)
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+type Kanji struct {
+	ID        int      `json:"id"`
+	Character string   `json:"character"`
+	Onyomi    []string `json:"onyomi"`
+	Kunyomi   []string `json:"kunyomi"`
+	Meanings  []string `json:"meanings"`
+	Strokes   int      `json:"strokes"`
+	Grade     int      `json:"grade"` // Jōyō school grade, 7 for secondary school
+	Level     int      `json:"level"` // JLPT level
+}
+
+const SourceKanji = "kanji"
+
+// jlptKanji is the Jōyō kanji shipped with the binary, each with its school
+// grade and JLPT level. Kanji outside the N5-N2 lists count as N1.
+//
+//go:embed jlpt_kanji.json
+var jlptKanji []byte
+
+func BundledKanji() ([]Kanji, error) {
+	kanji := []Kanji{}
+	if err := json.Unmarshal(jlptKanji, &kanji); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorParsingWords, err)
+	}
+	return kanji, nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+func migrateAnkiCache(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS anki_cards (
+			card_id INTEGER PRIMARY KEY,
+			source TEXT NOT NULL,
+			deck TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			question TEXT NOT NULL,
+			reading TEXT NOT NULL,
+			answer TEXT NOT NULL,
+			mod INTEGER NOT NULL,
+			cached_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS anki_pending_answers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			card_id INTEGER NOT NULL,
+			ease INTEGER NOT NULL,
+			mod INTEGER NOT NULL,
+			answered_at INTEGER NOT NULL
+		)
+	`)
+	return err
+}
+
+// CacheAnkiCards replaces the cached due cards of the source, keeping their
+// order.
+func (db *DB) CacheAnkiCards(source string, cards []data.AnkiCard, now time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM anki_cards WHERE source = ?`, source)
+	if err != nil {
+		return fmt.Errorf("error clearing anki cards: %s", err)
+	}
+
+	for i, c := range cards {
+		_, err = tx.Exec(`
+			INSERT OR REPLACE INTO anki_cards (card_id, source, deck, position, question, reading, answer, mod, cached_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.CardID, source, c.Deck, i, c.Question, c.Reading, c.Answer, c.Mod, now.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("error caching anki card: %s", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCachedAnkiCards returns the cached due cards of the source that were not
+// answered offline yet.
+func (db *DB) GetCachedAnkiCards(source string) ([]data.AnkiCard, error) {
+	rows, err := db.Query(`
+		SELECT card_id, source, deck, question, reading, answer, mod
+		FROM anki_cards
+		WHERE source = ?
+			AND card_id NOT IN (SELECT card_id FROM anki_pending_answers)
+		ORDER BY position`,
+		source,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching cached anki cards: %s", err)
+	}
+	defer rows.Close()
+
+	var cards []data.AnkiCard
+	for rows.Next() {
+		var c data.AnkiCard
+		if err := rows.Scan(&c.CardID, &c.Source, &c.Deck, &c.Question, &c.Reading, &c.Answer, &c.Mod); err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+// DeleteCachedAnkiCard drops a card answered in Anki from the cache.
+func (db *DB) DeleteCachedAnkiCard(cardID int64) error {
+	_, err := db.Exec(`DELETE FROM anki_cards WHERE card_id = ?`, cardID)
+	if err != nil {
+		return fmt.Errorf("error deleting cached anki card: %s", err)
+	}
+	return nil
+}
+
+func (db *DB) SavePendingAnkiAnswer(answer data.PendingAnkiAnswer) error {
+	_, err := db.Exec(`
+		INSERT INTO anki_pending_answers (card_id, ease, mod, answered_at)
+		VALUES (?, ?, ?, ?)`,
+		answer.CardID, answer.Ease, answer.Mod, answer.AnsweredAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving pending anki answer: %s", err)
+	}
+	return nil
+}
+
+// GetPendingAnkiAnswers returns the offline answers, oldest first.
+func (db *DB) GetPendingAnkiAnswers() ([]data.PendingAnkiAnswer, error) {
+	rows, err := db.Query(`
+		SELECT id, card_id, ease, mod, answered_at
+		FROM anki_pending_answers
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pending anki answers: %s", err)
+	}
+	defer rows.Close()
+
+	var answers []data.PendingAnkiAnswer
+	for rows.Next() {
+		var a data.PendingAnkiAnswer
+		var answeredAt int64
+		if err := rows.Scan(&a.ID, &a.CardID, &a.Ease, &a.Mod, &answeredAt); err != nil {
+			return nil, err
+		}
+		a.AnsweredAt = time.Unix(answeredAt, 0)
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+// DeletePendingAnkiAnswer drops a replayed or skipped answer, and its card
+// from the cache.
+func (db *DB) DeletePendingAnkiAnswer(answer data.PendingAnkiAnswer) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM anki_pending_answers WHERE id = ?`, answer.ID); err != nil {
+		return fmt.Errorf("error deleting pending anki answer: %s", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM anki_cards WHERE card_id = ?`, answer.CardID); err != nil {
+		return fmt.Errorf("error deleting cached anki card: %s", err)
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) CountPendingAnkiAnswers() (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM anki_pending_answers`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting pending anki answers: %s", err)
+	}
+	return count, nil
+}
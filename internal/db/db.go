@@ -5,13 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/srs"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+var ErrorNoWordsFound = errors.New("no words found")
+
 type DB struct {
 	*sql.DB
+
+	path       string
+	backupPath string
 }
 
 func Open(path string) (*DB, error) {
@@ -19,38 +26,13 @@ func Open(path string) (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DB{db}, nil
+	return &DB{DB: db, path: path}, nil
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *DB) Migrate() error {
-	_, err := db.Exec(`
-	    	CREATE TABLE IF NOT EXISTS words (
-					id INTEGER PRIMARY KEY AUTOINCREMENT,
-	    		word TEXT,
-	    		meaning TEXT,
-	    		furigana TEXT,
-	    		romaji TEXT,
-	    		level INTEGER,
-					seen INTEGER DEFAULT 0
-	    	)
-	`)
-	if err != nil {
-		return err
-	}
-
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS news_read (
-			nhk_id TEXT PRIMARY KEY,
-			read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	return err
-}
-
 func (db *DB) SeedVocab(words []data.Word) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -83,47 +65,196 @@ func (db *DB) SeedVocab(words []data.Word) error {
 	return nil
 }
 
-func (db *DB) GetNextWord(levels []int) (data.Word, error) {
-	if len(levels) == 0 {
-		return data.Word{}, fmt.Errorf("no levels provided")
+// UpdateVocab inserts the words that are not in the table yet, matching on
+// word and level so review progress of existing words is kept, even when the
+// reading was cleaned up since.
+func (db *DB) UpdateVocab(words []data.Word) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %s", err)
 	}
 
-	placeholders := make([]string, len(levels))
-	args := make([]interface{}, len(levels))
+	defer tx.Rollback()
 
-	for i, level := range levels {
-		placeholders[i] = "?"
-		args[i] = level
+	statement, err := tx.Prepare(`
+		INSERT INTO words (word, meaning, furigana, romaji, level)
+		SELECT ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM words WHERE word = ? AND level = ?
+		)`)
+	if err != nil {
+		return 0, fmt.Errorf("error preparing statement: %s", err)
 	}
 
-	// Safety: levels is an []int from config, not user input, so no SQL injection risk
+	defer statement.Close()
+
+	added := 0
+	for _, word := range words {
+		result, err := statement.Exec(
+			word.Word, word.Meaning, word.Furigana, word.Romaji, word.Level,
+			word.Word, word.Level,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting word: %s", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("error commiting transaction: %s", err)
+	}
+
+	return added, nil
+}
+
+// GetNextWord returns a random unseen word, leaving out the excluded ids.
+func (db *DB) GetNextWord(levels []int, exclude []int, decks ...string) (data.Word, error) {
+	if len(levels) == 0 && len(decks) == 0 {
+		return data.Word{}, fmt.Errorf("no levels provided")
+	}
+
+	scope, args := scopeClause(levels, decks)
+	excluded, excludeArgs := excludeClause("w.id", exclude)
+	args = append(args, excludeArgs...)
+
+	// Safety: levels and decks come from config and are bound as args, so no SQL injection risk
 	query := fmt.Sprintf(`
-		SELECT id, word, meaning, furigana, romaji, level
-		FROM words
-		WHERE seen = 0 AND level IN (%s)
+		SELECT w.id, w.word, w.meaning, w.furigana, w.romaji, w.level, %s
+		FROM words w
+		WHERE w.seen = 0 AND %s AND %s
 		ORDER BY RANDOM()
 		LIMIT 1`,
-		strings.Join(placeholders, ", "),
+		deckNameColumn, scope, excluded,
 	)
 
+	word, err := scanWord(db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Word{}, ErrorNoWordsFound
+		}
+
+		return data.Word{}, fmt.Errorf("error fetching word: %s", err)
+	}
+
+	return word, nil
+}
+
+// GetDueWord returns the most overdue word among those already introduced,
+// leaving out the excluded ids.
+func (db *DB) GetDueWord(levels []int, now time.Time, exclude []int, decks ...string) (data.Word, error) {
+	if len(levels) == 0 && len(decks) == 0 {
+		return data.Word{}, fmt.Errorf("no levels provided")
+	}
+
+	scope, args := scopeClause(levels, decks)
+	args = append(args, now.Unix())
+	excluded, excludeArgs := excludeClause("w.id", exclude)
+	args = append(args, excludeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT w.id, w.word, w.meaning, w.furigana, w.romaji, w.level, %s
+		FROM words w
+		JOIN word_progress p ON p.word_id = w.id
+		WHERE %s AND p.due_at <= ? AND %s
+		ORDER BY p.due_at ASC
+		LIMIT 1`,
+		deckNameColumn, scope, excluded,
+	)
+
+	word, err := scanWord(db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Word{}, ErrorNoWordsFound
+		}
+
+		return data.Word{}, fmt.Errorf("error fetching due word: %s", err)
+	}
+
+	return word, nil
+}
+
+func scanWord(row *sql.Row) (data.Word, error) {
 	var word data.Word
-	err := db.QueryRow(query, args...).Scan(
+	err := row.Scan(
 		&word.ID,
 		&word.Word,
 		&word.Meaning,
 		&word.Furigana,
 		&word.Romaji,
 		&word.Level,
+		&word.Deck,
+	)
+	return word, err
+}
+
+// GetWordProgress returns the review state of a word, or a fresh state if the
+// word has never been reviewed.
+func (db *DB) GetWordProgress(id int, now time.Time) (srs.State, error) {
+	return db.getProgress("word_progress", "word_id", id, now)
+}
+
+func (db *DB) SaveWordProgress(id int, state srs.State, now time.Time) error {
+	return db.saveProgress("word_progress", "word_id", id, state, now)
+}
+
+// getProgress and saveProgress work on any progress table keyed by idColumn,
+// so words and kanji share the same scheduling columns.
+func (db *DB) getProgress(table, idColumn string, id int, now time.Time) (srs.State, error) {
+	var (
+		state srs.State
+		dueAt int64
+	)
+	query := fmt.Sprintf(`
+		SELECT due_at, interval_days, ease, reps, lapses
+		FROM %s
+		WHERE %s = ?`,
+		table, idColumn,
 	)
+	err := db.QueryRow(query, id).Scan(&dueAt, &state.Interval, &state.Ease, &state.Reps, &state.Lapses)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return data.Word{}, fmt.Errorf("no words found")
+			return srs.NewState(now), nil
 		}
+		return srs.State{}, fmt.Errorf("error fetching progress: %s", err)
+	}
 
-		return data.Word{}, fmt.Errorf("error fetching word: %s", err)
+	state.Due = time.Unix(dueAt, 0)
+	return state, nil
+}
+
+func (db *DB) saveProgress(table, idColumn string, id int, state srs.State, now time.Time) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, due_at, interval_days, ease, reps, lapses, introduced_at, reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (%[2]s) DO UPDATE SET
+			due_at = excluded.due_at,
+			interval_days = excluded.interval_days,
+			ease = excluded.ease,
+			reps = excluded.reps,
+			lapses = excluded.lapses,
+			reviewed_at = excluded.reviewed_at`,
+		table, idColumn,
+	)
+	_, err := db.Exec(query, id, state.Due.Unix(), state.Interval, state.Ease, state.Reps, state.Lapses, now.Unix(), now.Unix())
+	if err != nil {
+		return fmt.Errorf("error saving progress: %s", err)
 	}
+	return nil
+}
 
-	return word, nil
+// CountIntroducedSince returns how many words got their first review after t.
+func (db *DB) CountIntroducedSince(t time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM word_progress WHERE introduced_at >= ?`, t.Unix()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting introduced words: %s", err)
+	}
+	return count, nil
 }
 
 func (db *DB) MarkWordAsSeen(id int) error {
@@ -139,6 +270,8 @@ func (db *DB) MarkWordAsSeen(id int) error {
 	return nil
 }
 
+// ResetSeenWords makes the words of the level new again. Their review
+// schedule is kept.
 func (db *DB) ResetSeenWords(level int) error {
 	_, err := db.Exec(`
 		UPDATE words
@@ -151,6 +284,21 @@ func (db *DB) ResetSeenWords(level int) error {
 	return nil
 }
 
+// ReintroduceWord counts a word shown again after a level reset as introduced
+// now, so it takes a slot of the daily new word limit.
+func (db *DB) ReintroduceWord(id int, now time.Time) error {
+	_, err := db.Exec(`
+		UPDATE word_progress
+		SET introduced_at = ?
+		WHERE word_id = ? AND word_id IN (SELECT id FROM words WHERE seen = 0)`,
+		now.Unix(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("error reintroducing word: %s", err)
+	}
+	return nil
+}
+
 func (db *DB) GetWordsCount(levels []int) (int, error) {
 	if len(levels) == 0 {
 		return 0, nil
@@ -158,18 +306,12 @@ func (db *DB) GetWordsCount(levels []int) (int, error) {
 
 	var count int
 
-	placeholders := make([]string, len(levels))
-	args := make([]interface{}, len(levels))
-
-	for i, level := range levels {
-		placeholders[i] = "?"
-		args[i] = level
-	}
+	placeholders, args := levelArgs(levels)
 
 	query := fmt.Sprintf(`
 		SELECT COUNT(*) 
 		FROM words
-		WHERE level IN (%s)`, strings.Join(placeholders, ", "))
+		WHERE level IN (%s)`, placeholders)
 
 	err := db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
@@ -178,6 +320,173 @@ func (db *DB) GetWordsCount(levels []int) (int, error) {
 	return count, nil
 }
 
+func (db *DB) InsertReview(review data.Review) error {
+	_, err := db.Exec(`
+		INSERT INTO reviews (source, card_id, grade, reviewed_at, duration_ms)
+		VALUES (?, ?, ?, ?, ?)`,
+		review.Source, review.CardID, review.Grade, review.ReviewedAt.Unix(), review.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting review: %s", err)
+	}
+	return nil
+}
+
+// GetDailyReviews returns review counts per local day, most recent first.
+// A review counts as passed when it was not answered with Again.
+func (db *DB) GetDailyReviews() ([]data.DailyReviews, error) {
+	rows, err := db.Query(`
+		SELECT date(reviewed_at, 'unixepoch', 'localtime') AS day,
+			COUNT(*),
+			SUM(CASE WHEN grade > 1 THEN 1 ELSE 0 END)
+		FROM reviews
+		GROUP BY day
+		ORDER BY day DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching daily reviews: %s", err)
+	}
+	defer rows.Close()
+
+	var days []data.DailyReviews
+	for rows.Next() {
+		var d data.DailyReviews
+		if err := rows.Scan(&d.Day, &d.Reviews, &d.Passed); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// GetLevelProgress returns, for each JLPT level, how many words have been
+// seen, are due at now, and are learned.
+func (db *DB) GetLevelProgress(now time.Time) ([]data.LevelProgress, error) {
+	rows, err := db.Query(`
+		SELECT w.level, COUNT(*), SUM(w.seen),
+			SUM(CASE WHEN p.due_at <= ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN p.interval_days >= ? THEN 1 ELSE 0 END)
+		FROM words w
+		LEFT JOIN word_progress p ON p.word_id = w.id
+		WHERE w.level > 0
+		GROUP BY w.level
+		ORDER BY w.level DESC`,
+		now.Unix(), data.LearnedInterval,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching level progress: %s", err)
+	}
+	defer rows.Close()
+
+	var progress []data.LevelProgress
+	for rows.Next() {
+		var p data.LevelProgress
+		if err := rows.Scan(&p.Level, &p.Total, &p.Seen, &p.Due, &p.Learned); err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, rows.Err()
+}
+
+// GetCompletedLevels returns the levels already reported as complete.
+func (db *DB) GetCompletedLevels() ([]int, error) {
+	rows, err := db.Query(`SELECT level FROM completed_levels ORDER BY level DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching completed levels: %s", err)
+	}
+	defer rows.Close()
+
+	var levels []int
+	for rows.Next() {
+		var level int
+		if err := rows.Scan(&level); err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, rows.Err()
+}
+
+// SaveCompletedLevel records that the level was completed, keeping the first
+// time it was.
+func (db *DB) SaveCompletedLevel(level int, now time.Time) error {
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO completed_levels (level, completed_at)
+		VALUES (?, ?)`,
+		level, now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving completed level: %s", err)
+	}
+	return nil
+}
+
+// DeleteCompletedLevel forgets the level was completed, so it is reported
+// again the next time it is.
+func (db *DB) DeleteCompletedLevel(level int) error {
+	_, err := db.Exec(`DELETE FROM completed_levels WHERE level = ?`, level)
+	if err != nil {
+		return fmt.Errorf("error deleting completed level: %s", err)
+	}
+	return nil
+}
+
+// GetWordsWithProgress returns the words of the levels with their review
+// progress, easiest level first.
+func (db *DB) GetWordsWithProgress(levels []int) ([]data.WordProgress, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+
+	placeholders, args := levelArgs(levels)
+	query := fmt.Sprintf(`
+		SELECT w.id, w.word, w.meaning, w.furigana, w.romaji, w.level, w.seen, COALESCE(p.interval_days, 0)
+		FROM words w
+		LEFT JOIN word_progress p ON p.word_id = w.id
+		WHERE w.level IN (%s)
+		ORDER BY w.level DESC, w.id`, placeholders)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching words: %s", err)
+	}
+	defer rows.Close()
+
+	var words []data.WordProgress
+	for rows.Next() {
+		var w data.WordProgress
+		err := rows.Scan(&w.ID, &w.Word.Word, &w.Meaning, &w.Furigana, &w.Romaji, &w.Level, &w.Seen, &w.Interval)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
+
+// levelArgs builds the IN placeholders and query args for a list of levels.
+func levelArgs(levels []int) (string, []interface{}) {
+	placeholders := make([]string, len(levels))
+	args := make([]interface{}, len(levels))
+
+	for i, level := range levels {
+		placeholders[i] = "?"
+		args[i] = level
+	}
+
+	return strings.Join(placeholders, ", "), args
+}
+
+// excludeClause leaves out the given ids, like words skipped for now.
+func excludeClause(column string, ids []int) (string, []interface{}) {
+	if len(ids) == 0 {
+		return "1 = 1", nil
+	}
+
+	placeholders, args := levelArgs(ids)
+	return fmt.Sprintf("%s NOT IN (%s)", column, placeholders), args
+}
+
 func (db *DB) MarkNewsAsRead(nhkID string) error {
 	_, err := db.Exec(`INSERT OR IGNORE INTO news_read (nhk_id) VALUES (?)`, nhkID)
 	return err
@@ -1,9 +1,13 @@
 package db
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/srs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -74,7 +78,7 @@ func TestGetNextWord(t *testing.T) {
 	seedTestWords(t, db)
 
 	t.Run("returns word from specified levels", func(t *testing.T) {
-		word, err := db.GetNextWord([]int{5})
+		word, err := db.GetNextWord([]int{5}, nil)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 5, word.Level)
@@ -82,21 +86,21 @@ func TestGetNextWord(t *testing.T) {
 	})
 
 	t.Run("returns word from multiple levels", func(t *testing.T) {
-		word, err := db.GetNextWord([]int{4, 5})
+		word, err := db.GetNextWord([]int{4, 5}, nil)
 
 		assert.NoError(t, err)
 		assert.Contains(t, []int{4, 5}, word.Level)
 	})
 
 	t.Run("returns error when no levels provided", func(t *testing.T) {
-		_, err := db.GetNextWord([]int{})
+		_, err := db.GetNextWord([]int{}, nil)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no levels provided")
 	})
 
 	t.Run("returns error when no words found", func(t *testing.T) {
-		_, err := db.GetNextWord([]int{1})
+		_, err := db.GetNextWord([]int{1}, nil)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no words found")
@@ -177,13 +181,13 @@ func TestGetNextWordRespectsSeenFlag(t *testing.T) {
 	}
 	require.NoError(t, db.SeedVocab(words))
 
-	word, err := db.GetNextWord([]int{5})
+	word, err := db.GetNextWord([]int{5}, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "犬", word.Word)
 
 	require.NoError(t, db.MarkWordAsSeen(1))
 
-	_, err = db.GetNextWord([]int{5})
+	_, err = db.GetNextWord([]int{5}, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no words found")
 }
@@ -197,13 +201,496 @@ func TestGetNextWordReturnsValidID(t *testing.T) {
 	}
 	require.NoError(t, db.SeedVocab(words))
 
-	word, err := db.GetNextWord([]int{5})
+	word, err := db.GetNextWord([]int{5}, nil)
 	require.NoError(t, err)
 	assert.NotZero(t, word.ID, "GetNextWord should return a word with a valid ID")
 
 	require.NoError(t, db.MarkWordAsSeen(word.ID))
 
-	_, err = db.GetNextWord([]int{5})
+	_, err = db.GetNextWord([]int{5}, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no words found")
 }
+
+func TestWordProgress(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	t.Run("returns fresh state for unreviewed word", func(t *testing.T) {
+		state, err := db.GetWordProgress(1, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, srs.NewState(now), state)
+	})
+
+	t.Run("saves and loads state", func(t *testing.T) {
+		saved := srs.Schedule(srs.NewState(now), srs.Good, now)
+		require.NoError(t, db.SaveWordProgress(1, saved, now))
+
+		state, err := db.GetWordProgress(1, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, saved.Interval, state.Interval)
+		assert.Equal(t, saved.Ease, state.Ease)
+		assert.Equal(t, saved.Reps, state.Reps)
+		assert.True(t, saved.Due.Equal(state.Due))
+	})
+
+	t.Run("keeps introduction time on update", func(t *testing.T) {
+		later := now.Add(48 * time.Hour)
+		require.NoError(t, db.SaveWordProgress(1, srs.NewState(later), later))
+
+		count, err := db.CountIntroducedSince(now.Add(24 * time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestGetDueWord(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	t.Run("returns error when nothing is due", func(t *testing.T) {
+		_, err := db.GetDueWord([]int{5}, now, nil)
+
+		assert.ErrorIs(t, err, ErrorNoWordsFound)
+	})
+
+	t.Run("returns most overdue word", func(t *testing.T) {
+		require.NoError(t, db.SaveWordProgress(1, srs.State{Due: now.Add(-time.Hour), Ease: srs.DefaultEase}, now))
+		require.NoError(t, db.SaveWordProgress(2, srs.State{Due: now.Add(-48 * time.Hour), Ease: srs.DefaultEase}, now))
+
+		word, err := db.GetDueWord([]int{5}, now, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, word.ID)
+	})
+
+	t.Run("ignores words due later", func(t *testing.T) {
+		require.NoError(t, db.SaveWordProgress(3, srs.State{Due: now.Add(time.Hour), Ease: srs.DefaultEase}, now))
+
+		_, err := db.GetDueWord([]int{4}, now, nil)
+
+		assert.ErrorIs(t, err, ErrorNoWordsFound)
+	})
+}
+
+func TestResetSeenWordsKeepsProgress(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+
+	now := time.Now()
+	require.NoError(t, db.SaveWordProgress(1, srs.NewState(now), now))
+	require.NoError(t, db.SaveWordProgress(3, srs.NewState(now), now))
+
+	require.NoError(t, db.ResetSeenWords(5))
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM word_progress").Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCompletedLevels(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	require.NoError(t, db.SaveCompletedLevel(4, now))
+	require.NoError(t, db.SaveCompletedLevel(5, now))
+	require.NoError(t, db.SaveCompletedLevel(5, now))
+
+	levels, err := db.GetCompletedLevels()
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 4}, levels)
+
+	require.NoError(t, db.DeleteCompletedLevel(5))
+
+	levels, err = db.GetCompletedLevels()
+	require.NoError(t, err)
+	assert.Equal(t, []int{4}, levels)
+}
+
+func TestInsertReview(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	reviewedAt := time.Date(2025, 1, 15, 12, 0, 0, 0, time.Local)
+	err := db.InsertReview(data.Review{
+		Source:     data.SourceAnki,
+		CardID:     1700000000000,
+		Grade:      3,
+		ReviewedAt: reviewedAt,
+		Duration:   1500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var (
+		source   string
+		cardID   int64
+		duration int64
+	)
+	err = db.QueryRow("SELECT source, card_id, duration_ms FROM reviews").Scan(&source, &cardID, &duration)
+	require.NoError(t, err)
+	assert.Equal(t, data.SourceAnki, source)
+	assert.Equal(t, int64(1700000000000), cardID)
+	assert.Equal(t, int64(1500), duration)
+
+	days, err := db.GetDailyReviews()
+	require.NoError(t, err)
+	assert.Equal(t, []data.DailyReviews{{Day: "2025-01-15", Reviews: 1, Passed: 1}}, days)
+}
+
+func TestUpdateVocab(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+	require.NoError(t, db.MarkWordAsSeen(1))
+
+	added, err := db.UpdateVocab([]data.Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Romaji: "inu", Level: 5},
+		{Word: "鳥", Meaning: "bird", Furigana: "とり", Romaji: "tori", Level: 5},
+		// The word API may still have a reading the bundled words cleaned up.
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ(名)", Romaji: "inu", Level: 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	count, err := db.GetWordsCount([]int{5})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	var seen int
+	err = db.QueryRow("SELECT seen FROM words WHERE id = 1").Scan(&seen)
+	require.NoError(t, err)
+	assert.Equal(t, 1, seen, "existing words keep their progress")
+}
+
+func TestImportDeck(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+
+	words := []data.Word{
+		{Word: "関数", Furigana: "かんすう", Meaning: "function"},
+		{Word: "変数", Furigana: "へんすう", Meaning: "variable"},
+	}
+
+	added, err := db.ImportDeck("IT", words)
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	t.Run("skips words already in deck", func(t *testing.T) {
+		added, err := db.ImportDeck("IT", words[:1])
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, added)
+	})
+
+	t.Run("lists decks with word counts", func(t *testing.T) {
+		decks, err := db.GetDecks()
+
+		assert.NoError(t, err)
+		require.Len(t, decks, 1)
+		assert.Equal(t, "IT", decks[0].Name)
+		assert.Equal(t, 2, decks[0].WordCount)
+	})
+
+	t.Run("deck words are selectable by deck name", func(t *testing.T) {
+		word, err := db.GetNextWord(nil, nil, "IT")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "IT", word.Deck)
+		assert.Equal(t, data.CustomLevel, word.Level)
+		assert.Contains(t, []string{"関数", "変数"}, word.Word)
+	})
+
+	t.Run("JLPT levels and decks combine", func(t *testing.T) {
+		seen := map[string]bool{}
+		for range 50 {
+			word, err := db.GetNextWord([]int{2}, nil, "IT")
+			require.NoError(t, err)
+			seen[word.Word] = true
+		}
+
+		assert.True(t, seen["経済"])
+		assert.True(t, seen["関数"] || seen["変数"])
+	})
+
+	t.Run("deck words are not counted as JLPT words", func(t *testing.T) {
+		count, err := db.GetWordsCount([]int{1, 2, 3, 4, 5})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, count)
+	})
+}
+
+func TestGetLevelProgress(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedTestWords(t, db)
+	_, err := db.ImportDeck("IT", []data.Word{{Word: "関数"}})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.MarkWordAsSeen(1))
+	require.NoError(t, db.MarkWordAsSeen(2))
+	require.NoError(t, db.SaveWordProgress(1, srs.State{Due: now.Add(-time.Hour), Ease: srs.DefaultEase, Interval: 3}, now))
+	require.NoError(t, db.SaveWordProgress(2, srs.State{Due: now.AddDate(0, 0, 30), Ease: srs.DefaultEase, Interval: 30}, now))
+
+	progress, err := db.GetLevelProgress(now)
+
+	require.NoError(t, err)
+	assert.Equal(t, []data.LevelProgress{
+		{Level: 5, Total: 2, Seen: 2, Due: 1, Learned: 1},
+		{Level: 4, Total: 2},
+		{Level: 2, Total: 1},
+	}, progress)
+}
+
+func seedTestKanji(t *testing.T, db *DB) {
+	kanji := []data.Kanji{
+		{Character: "日", Onyomi: []string{"ニチ", "ジツ"}, Kunyomi: []string{"ひ", "か"}, Meanings: []string{"day", "sun"}, Strokes: 4, Grade: 1, Level: 5},
+		{Character: "月", Onyomi: []string{"ゲツ", "ガツ"}, Kunyomi: []string{"つき"}, Meanings: []string{"month", "moon"}, Strokes: 4, Grade: 1, Level: 5},
+		{Character: "経", Onyomi: []string{"ケイ"}, Kunyomi: []string{"へ.る"}, Meanings: []string{"sutra", "pass through"}, Strokes: 11, Grade: 5, Level: 2},
+	}
+	added, err := db.SeedKanji(kanji)
+	require.NoError(t, err)
+	require.Equal(t, 3, added)
+}
+
+func TestSeedKanji(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	seedTestKanji(t, db)
+
+	added, err := db.SeedKanji([]data.Kanji{{Character: "日", Meanings: []string{"day"}, Level: 5}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, added)
+
+	count, err := db.GetKanjiCount()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestGetNextKanji(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	seedTestKanji(t, db)
+
+	kanji, err := db.GetNextKanji([]int{2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "経", kanji.Character)
+	assert.Equal(t, []string{"ケイ"}, kanji.Onyomi)
+	assert.Equal(t, []string{"sutra", "pass through"}, kanji.Meanings)
+	assert.Equal(t, 11, kanji.Strokes)
+
+	require.NoError(t, db.MarkKanjiAsSeen(kanji.ID))
+
+	_, err = db.GetNextKanji([]int{2}, nil)
+	assert.ErrorIs(t, err, ErrorNoKanjiFound)
+}
+
+func TestKanjiListsKeepCommas(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.SeedKanji([]data.Kanji{
+		{Character: "貝", Onyomi: []string{"バイ"}, Meanings: []string{"shellfish", "shell, as money"}, Strokes: 7, Level: 2},
+	})
+	require.NoError(t, err)
+
+	kanji, err := db.GetNextKanji([]int{2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shellfish", "shell, as money"}, kanji.Meanings)
+	assert.Equal(t, []string{"バイ"}, kanji.Onyomi)
+	assert.Nil(t, kanji.Kunyomi)
+}
+
+func TestMigrateWordFixes(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// Rows seeded from the vocabulary before it was cleaned up.
+	_, err := db.Exec(`
+		INSERT INTO words (word, meaning, furigana, romaji, level) VALUES
+			('いえ', 'TODO same as いいえ?', 'いえ', 'ie', 3),
+			('ね', 'value, price, cost, worth, merit', '（感）', '()', 3),
+			('税', '', 'ぜい', 'zei', 3),
+			('あひら', '', 'あひら', 'ahira', 2),
+			('犬', 'dog', 'いぬ', 'inu', 5)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO word_progress (word_id, due_at, ease, introduced_at, reviewed_at)
+		SELECT id, 0, 2.5, 0, 0 FROM words WHERE word = 'あひら'`)
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, migrateWordFixes(tx))
+	require.NoError(t, tx.Commit())
+
+	rows, err := db.Query(`SELECT word, meaning, furigana, romaji FROM words ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var words []string
+	for rows.Next() {
+		var word, meaning, furigana, romaji string
+		require.NoError(t, rows.Scan(&word, &meaning, &furigana, &romaji))
+		words = append(words, strings.Join([]string{word, meaning, furigana, romaji}, "|"))
+	}
+	assert.Equal(t, []string{
+		"いえ|no, not at all|いえ|ie",
+		"ね|hey, say, you know||ne",
+		"税|tax|ぜい|zei",
+		"犬|dog|いぬ|inu",
+	}, words)
+
+	var progress int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM word_progress`).Scan(&progress))
+	assert.Zero(t, progress)
+}
+
+func TestGetDueKanji(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	seedTestKanji(t, db)
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	_, err := db.GetDueKanji([]int{5}, now, nil)
+	assert.ErrorIs(t, err, ErrorNoKanjiFound)
+
+	require.NoError(t, db.SaveKanjiProgress(2, srs.State{Due: now.Add(-time.Hour), Ease: srs.DefaultEase}, now))
+	require.NoError(t, db.SaveKanjiProgress(1, srs.State{Due: now.Add(time.Hour), Ease: srs.DefaultEase}, now))
+
+	kanji, err := db.GetDueKanji([]int{5}, now, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "月", kanji.Character)
+
+	state, err := db.GetKanjiProgress(1, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour).Unix(), state.Due.Unix())
+
+	count, err := db.CountKanjiIntroducedSince(now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestSentences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	_, ok, err := db.GetSentenceLookup("雨")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	sentences := []data.Sentence{
+		{NhkID: "ne1", Title: "天気", Text: "雨が降りました。"},
+		{NhkID: "ne2", Title: "明日", Text: "明日も雨です。"},
+	}
+	require.NoError(t, db.SaveSentences("雨", sentences, now))
+	require.NoError(t, db.SaveSentences("経済", nil, now))
+
+	fetchedAt, ok, err := db.GetSentenceLookup("雨")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix(), fetchedAt.Unix())
+
+	cached, err := db.GetSentences("雨")
+	require.NoError(t, err)
+	require.Len(t, cached, 2)
+	assert.Equal(t, "雨", cached[0].Word)
+	assert.Equal(t, "雨が降りました。", cached[0].Text)
+
+	_, ok, err = db.GetSentenceLookup("経済")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, db.SaveSentences("雨", sentences[1:], now))
+	cached, err = db.GetSentences("雨")
+	require.NoError(t, err)
+	assert.Len(t, cached, 1)
+}
+
+func TestMigrateRecordsSchemaVersion(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	require.NoError(t, db.Migrate())
+
+	migrations, err := db.Migrations()
+	require.NoError(t, err)
+	require.Len(t, migrations, LatestSchemaVersion())
+	for _, m := range migrations {
+		assert.NotNil(t, m.AppliedAt, "migration %d not applied", m.Version)
+	}
+	assert.Empty(t, db.BackupPath())
+}
+
+func TestMigrateBacksUpExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keiko.db")
+
+	// A database created before schema versioning only has the original
+	// tables.
+	old, err := Open(path)
+	require.NoError(t, err)
+	_, err = old.Exec(`CREATE TABLE words (id INTEGER PRIMARY KEY AUTOINCREMENT, word TEXT, meaning TEXT, furigana TEXT, romaji TEXT, level INTEGER, seen INTEGER DEFAULT 0)`)
+	require.NoError(t, err)
+	_, err = old.Exec(`INSERT INTO words (word, meaning, furigana, romaji, level) VALUES ('犬', 'dog', 'いぬ', 'inu', 5)`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Migrate())
+
+	require.NotEmpty(t, db.BackupPath())
+	backups, err := db.Backups()
+	require.NoError(t, err)
+	assert.Equal(t, []string{db.BackupPath()}, backups)
+
+	backup, err := Open(db.BackupPath())
+	require.NoError(t, err)
+	defer backup.Close()
+
+	var count int
+	require.NoError(t, backup.QueryRow(`SELECT COUNT(*) FROM words`).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	count, err = db.GetWordsCount([]int{5})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestMigrateSkipsBackupForNewDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keiko.db")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Migrate())
+
+	assert.Empty(t, db.BackupPath())
+	backups, err := db.Backups()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
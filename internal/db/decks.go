@@ -0,0 +1,157 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+// deckNameColumn selects the name of the deck a word was imported into, or an
+// empty string for JLPT words. It expects words to be aliased as w.
+const deckNameColumn = `COALESCE((
+			SELECT d.name FROM deck_words dw JOIN decks d ON d.id = dw.deck_id
+			WHERE dw.word_id = w.id LIMIT 1), '')`
+
+func migrateDecks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS decks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS deck_words (
+			deck_id INTEGER NOT NULL REFERENCES decks(id),
+			word_id INTEGER NOT NULL REFERENCES words(id),
+			PRIMARY KEY (deck_id, word_id)
+		)
+	`)
+	return err
+}
+
+// ImportDeck stores the words in the named deck, creating it if needed. Words
+// already in the deck (same word and reading) are skipped. Imported words live
+// in the words table with data.CustomLevel so they share review scheduling with
+// the JLPT vocabulary.
+func (db *DB) ImportDeck(name string, words []data.Word) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO decks (name) VALUES (?)`, name)
+	if err != nil {
+		return 0, fmt.Errorf("error creating deck: %s", err)
+	}
+
+	var deckID int64
+	err = tx.QueryRow(`SELECT id FROM decks WHERE name = ?`, name).Scan(&deckID)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching deck: %s", err)
+	}
+
+	added := 0
+	for _, word := range words {
+		var exists bool
+		err = tx.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM deck_words dw JOIN words w ON w.id = dw.word_id
+				WHERE dw.deck_id = ? AND w.word = ? AND w.furigana = ?
+			)`,
+			deckID, word.Word, word.Furigana,
+		).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("error checking word: %s", err)
+		}
+		if exists {
+			continue
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO words (word, meaning, furigana, romaji, level)
+			VALUES (?, ?, ?, ?, ?)`,
+			word.Word, word.Meaning, word.Furigana, word.Romaji, data.CustomLevel,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting word: %s", err)
+		}
+
+		wordID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(`INSERT INTO deck_words (deck_id, word_id) VALUES (?, ?)`, deckID, wordID)
+		if err != nil {
+			return 0, fmt.Errorf("error adding word to deck: %s", err)
+		}
+		added++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("error commiting transaction: %s", err)
+	}
+
+	return added, nil
+}
+
+func (db *DB) GetDecks() ([]data.Deck, error) {
+	rows, err := db.Query(`
+		SELECT d.id, d.name, COUNT(dw.word_id)
+		FROM decks d
+		LEFT JOIN deck_words dw ON dw.deck_id = d.id
+		GROUP BY d.id
+		ORDER BY d.name`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching decks: %s", err)
+	}
+	defer rows.Close()
+
+	var decks []data.Deck
+	for rows.Next() {
+		var d data.Deck
+		if err := rows.Scan(&d.ID, &d.Name, &d.WordCount); err != nil {
+			return nil, err
+		}
+		decks = append(decks, d)
+	}
+	return decks, rows.Err()
+}
+
+// scopeClause restricts words (aliased as w) to the given JLPT levels and
+// imported decks.
+func scopeClause(levels []int, decks []string) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	if len(levels) > 0 {
+		placeholders, levelArgs := levelArgs(levels)
+		clauses = append(clauses, fmt.Sprintf("w.level IN (%s)", placeholders))
+		args = append(args, levelArgs...)
+	}
+
+	if len(decks) > 0 {
+		placeholders := make([]string, len(decks))
+		for i, deck := range decks {
+			placeholders[i] = "?"
+			args = append(args, deck)
+		}
+		clauses = append(clauses, fmt.Sprintf(`w.id IN (
+			SELECT dw.word_id FROM deck_words dw JOIN decks d ON d.id = dw.deck_id
+			WHERE d.name IN (%s))`, strings.Join(placeholders, ", ")))
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/srs"
+)
+
+var ErrorNoKanjiFound = errors.New("no kanji found")
+
+func migrateKanji(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS kanji (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			character TEXT UNIQUE NOT NULL,
+			onyomi TEXT NOT NULL DEFAULT '[]',
+			kunyomi TEXT NOT NULL DEFAULT '[]',
+			meanings TEXT NOT NULL DEFAULT '[]',
+			strokes INTEGER NOT NULL DEFAULT 0,
+			grade INTEGER NOT NULL DEFAULT 0,
+			level INTEGER NOT NULL DEFAULT 0,
+			seen INTEGER DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS kanji_progress (
+			kanji_id INTEGER PRIMARY KEY REFERENCES kanji(id),
+			due_at INTEGER NOT NULL,
+			interval_days INTEGER NOT NULL DEFAULT 0,
+			ease REAL NOT NULL,
+			reps INTEGER NOT NULL DEFAULT 0,
+			lapses INTEGER NOT NULL DEFAULT 0,
+			introduced_at INTEGER NOT NULL,
+			reviewed_at INTEGER NOT NULL
+		)
+	`)
+	return err
+}
+
+// encodeList stores a list of readings or meanings in a single column.
+func encodeList(list []string) string {
+	if list == nil {
+		list = []string{}
+	}
+	encoded, _ := json.Marshal(list)
+	return string(encoded)
+}
+
+func decodeList(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(s), &list); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// SeedKanji inserts the kanji that are not in the table yet and returns how
+// many were added.
+func (db *DB) SeedKanji(kanji []data.Kanji) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare(`
+		INSERT OR IGNORE INTO kanji (character, onyomi, kunyomi, meanings, strokes, grade, level)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		`)
+	if err != nil {
+		return 0, fmt.Errorf("error preparing statement: %s", err)
+	}
+
+	defer statement.Close()
+
+	added := 0
+	for _, k := range kanji {
+		result, err := statement.Exec(
+			k.Character,
+			encodeList(k.Onyomi),
+			encodeList(k.Kunyomi),
+			encodeList(k.Meanings),
+			k.Strokes, k.Grade, k.Level,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting kanji: %s", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("error commiting transaction: %s", err)
+	}
+
+	return added, nil
+}
+
+// GetNextKanji returns a random kanji of the given levels that has not been
+// seen yet, leaving out the excluded ids.
+func (db *DB) GetNextKanji(levels []int, exclude []int) (data.Kanji, error) {
+	if len(levels) == 0 {
+		return data.Kanji{}, fmt.Errorf("no levels provided")
+	}
+
+	placeholders, args := levelArgs(levels)
+	excluded, excludeArgs := excludeClause("k.id", exclude)
+	args = append(args, excludeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT k.id, k.character, k.onyomi, k.kunyomi, k.meanings, k.strokes, k.grade, k.level
+		FROM kanji k
+		WHERE k.seen = 0 AND k.level IN (%s) AND %s
+		ORDER BY RANDOM()
+		LIMIT 1`,
+		placeholders, excluded,
+	)
+
+	kanji, err := scanKanji(db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Kanji{}, ErrorNoKanjiFound
+		}
+
+		return data.Kanji{}, fmt.Errorf("error fetching kanji: %s", err)
+	}
+
+	return kanji, nil
+}
+
+// GetDueKanji returns the most overdue kanji among those already introduced,
+// leaving out the excluded ids.
+func (db *DB) GetDueKanji(levels []int, now time.Time, exclude []int) (data.Kanji, error) {
+	if len(levels) == 0 {
+		return data.Kanji{}, fmt.Errorf("no levels provided")
+	}
+
+	placeholders, args := levelArgs(levels)
+	args = append(args, now.Unix())
+	excluded, excludeArgs := excludeClause("k.id", exclude)
+	args = append(args, excludeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT k.id, k.character, k.onyomi, k.kunyomi, k.meanings, k.strokes, k.grade, k.level
+		FROM kanji k
+		JOIN kanji_progress p ON p.kanji_id = k.id
+		WHERE k.level IN (%s) AND p.due_at <= ? AND %s
+		ORDER BY p.due_at ASC
+		LIMIT 1`,
+		placeholders, excluded,
+	)
+
+	kanji, err := scanKanji(db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Kanji{}, ErrorNoKanjiFound
+		}
+
+		return data.Kanji{}, fmt.Errorf("error fetching due kanji: %s", err)
+	}
+
+	return kanji, nil
+}
+
+func scanKanji(row *sql.Row) (data.Kanji, error) {
+	var (
+		kanji                     data.Kanji
+		onyomi, kunyomi, meanings string
+	)
+	err := row.Scan(
+		&kanji.ID,
+		&kanji.Character,
+		&onyomi,
+		&kunyomi,
+		&meanings,
+		&kanji.Strokes,
+		&kanji.Grade,
+		&kanji.Level,
+	)
+	if err != nil {
+		return kanji, err
+	}
+
+	if kanji.Onyomi, err = decodeList(onyomi); err != nil {
+		return kanji, fmt.Errorf("error decoding readings of %s: %s", kanji.Character, err)
+	}
+	if kanji.Kunyomi, err = decodeList(kunyomi); err != nil {
+		return kanji, fmt.Errorf("error decoding readings of %s: %s", kanji.Character, err)
+	}
+	if kanji.Meanings, err = decodeList(meanings); err != nil {
+		return kanji, fmt.Errorf("error decoding meanings of %s: %s", kanji.Character, err)
+	}
+	return kanji, nil
+}
+
+func (db *DB) GetKanjiProgress(id int, now time.Time) (srs.State, error) {
+	return db.getProgress("kanji_progress", "kanji_id", id, now)
+}
+
+func (db *DB) SaveKanjiProgress(id int, state srs.State, now time.Time) error {
+	return db.saveProgress("kanji_progress", "kanji_id", id, state, now)
+}
+
+// CountKanjiIntroducedSince returns how many kanji got their first review
+// after t.
+func (db *DB) CountKanjiIntroducedSince(t time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM kanji_progress WHERE introduced_at >= ?`, t.Unix()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting introduced kanji: %s", err)
+	}
+	return count, nil
+}
+
+func (db *DB) MarkKanjiAsSeen(id int) error {
+	_, err := db.Exec(`
+		UPDATE kanji
+		SET seen = 1
+		WHERE id = ?`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error marking kanji as seen: %s", err)
+	}
+	return nil
+}
+
+func (db *DB) GetKanjiCount() (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM kanji`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error getting kanji count: %s", err)
+	}
+	return count, nil
+}
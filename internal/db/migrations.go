@@ -0,0 +1,249 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// migration is one step of the schema history. Steps run in order inside a
+// transaction and are recorded in schema_migrations, so a step never runs
+// twice. Add new steps at the end; never edit a released one.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "create words and news_read", migrateWords},
+	{2, "add word progress and review log", migrateProgress},
+	{3, "add imported decks", migrateDecks},
+	{4, "add kanji", migrateKanji},
+	{5, "add example sentence cache", migrateSentences},
+	{6, "add offline anki cache", migrateAnkiCache},
+	{7, "add completed levels", migrateCompletedLevels},
+	{8, "clean up bundled words", migrateWordFixes},
+}
+
+// Migration describes a schema step and when it was applied, if it was.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate applies the pending migrations. An existing database is backed up
+// next to itself before the first pending step runs.
+func (db *DB) Migrate() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %s", err)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current >= LatestSchemaVersion() {
+		return nil
+	}
+
+	if err := db.backup(current); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := db.apply(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) apply(m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("error applying migration %d (%s): %s", m.version, m.name, err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error recording migration %d: %s", m.version, err)
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) SchemaVersion() (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching schema version: %s", err)
+	}
+	return version, nil
+}
+
+// Migrations lists every known migration with the time it was applied.
+func (db *DB) Migrations() ([]Migration, error) {
+	applied := make(map[int]time.Time)
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching migrations: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt int64
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = time.Unix(appliedAt, 0)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		status := Migration{Version: m.version, Name: m.name}
+		if t, ok := applied[m.version]; ok {
+			status.AppliedAt = &t
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// backup copies the database before migrating it. New and in-memory
+// databases have nothing worth keeping and are skipped.
+func (db *DB) backup(version int) error {
+	if db.path == "" || strings.Contains(db.path, ":memory:") || strings.Contains(db.path, "mode=memory") {
+		return nil
+	}
+
+	var tables int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`,
+	).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("error inspecting database: %s", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	path := fmt.Sprintf("%s.v%d-%s.bak", db.path, version, time.Now().Format("20060102-150405"))
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("error backing up database: %s", err)
+	}
+
+	db.backupPath = path
+	return nil
+}
+
+// BackupPath is the backup made by the last Migrate, if any.
+func (db *DB) BackupPath() string {
+	return db.backupPath
+}
+
+// Backups lists the migration backups next to the database, oldest first.
+func (db *DB) Backups() ([]string, error) {
+	return filepath.Glob(db.path + ".v*.bak")
+}
+
+func migrateWords(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS words (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			word TEXT,
+			meaning TEXT,
+			furigana TEXT,
+			romaji TEXT,
+			level INTEGER,
+			seen INTEGER DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS news_read (
+			nhk_id TEXT PRIMARY KEY,
+			read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+func migrateProgress(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS word_progress (
+			word_id INTEGER PRIMARY KEY REFERENCES words(id),
+			due_at INTEGER NOT NULL,
+			interval_days INTEGER NOT NULL DEFAULT 0,
+			ease REAL NOT NULL,
+			reps INTEGER NOT NULL DEFAULT 0,
+			lapses INTEGER NOT NULL DEFAULT 0,
+			introduced_at INTEGER NOT NULL,
+			reviewed_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			card_id INTEGER NOT NULL,
+			grade INTEGER NOT NULL,
+			reviewed_at INTEGER NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0
+		)
+	`)
+	return err
+}
+
+func migrateCompletedLevels(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS completed_levels (
+			level INTEGER PRIMARY KEY,
+			completed_at INTEGER NOT NULL
+		)
+	`)
+	return err
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+func migrateSentences(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS sentence_lookups (
+			word TEXT PRIMARY KEY,
+			fetched_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS sentences (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			word TEXT NOT NULL,
+			nhk_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			text TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_sentences_word ON sentences(word)`)
+	return err
+}
+
+// GetSentenceLookup returns when sentences for the word were last fetched,
+// and false if they never were.
+func (db *DB) GetSentenceLookup(word string) (time.Time, bool, error) {
+	var fetchedAt int64
+	err := db.QueryRow(`SELECT fetched_at FROM sentence_lookups WHERE word = ?`, word).Scan(&fetchedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("error fetching sentence lookup: %s", err)
+	}
+	return time.Unix(fetchedAt, 0), true, nil
+}
+
+func (db *DB) GetSentences(word string) ([]data.Sentence, error) {
+	rows, err := db.Query(`
+		SELECT word, nhk_id, title, text
+		FROM sentences
+		WHERE word = ?
+		ORDER BY id`,
+		word,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching sentences: %s", err)
+	}
+	defer rows.Close()
+
+	var sentences []data.Sentence
+	for rows.Next() {
+		var s data.Sentence
+		if err := rows.Scan(&s.Word, &s.NhkID, &s.Title, &s.Text); err != nil {
+			return nil, err
+		}
+		sentences = append(sentences, s)
+	}
+	return sentences, rows.Err()
+}
+
+// SaveSentences replaces the cached sentences of the word. An empty list is
+// cached too, so words missing from the corpus are not looked up every time.
+func (db *DB) SaveSentences(word string, sentences []data.Sentence, now time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %s", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sentences WHERE word = ?`, word)
+	if err != nil {
+		return fmt.Errorf("error clearing sentences: %s", err)
+	}
+
+	for _, s := range sentences {
+		_, err = tx.Exec(`
+			INSERT INTO sentences (word, nhk_id, title, text)
+			VALUES (?, ?, ?, ?)`,
+			word, s.NhkID, s.Title, s.Text,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting sentence: %s", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO sentence_lookups (word, fetched_at)
+		VALUES (?, ?)
+		ON CONFLICT (word) DO UPDATE SET fetched_at = excluded.fetched_at`,
+		word, now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving sentence lookup: %s", err)
+	}
+
+	return tx.Commit()
+}
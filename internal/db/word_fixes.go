@@ -0,0 +1,122 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+// wordFix is a JLPT word as first seeded, with what the cleaned-up
+// vocabulary has instead, or drop when it was removed.
+type wordFix struct {
+	level    int
+	word     string
+	furigana string
+	fixed    data.Word
+	drop     bool
+}
+
+// wordFixes are the blank, placeholder and mismatched words of the original
+// vocabulary. The list is frozen with migration 8: later edits to the
+// bundled words must not change what it does.
+var wordFixes = []wordFix{
+	{level: 3, word: "だが", furigana: "だが", fixed: data.Word{Meaning: "but, however", Furigana: "だが", Romaji: "daga"}},
+	{level: 3, word: "いえ", furigana: "いえ", fixed: data.Word{Meaning: "no, not at all", Furigana: "いえ", Romaji: "ie"}},
+	{level: 3, word: "ね", furigana: "（感）", fixed: data.Word{Meaning: "hey, say, you know", Furigana: "", Romaji: "ne"}},
+	{level: 3, word: "それと", furigana: "", fixed: data.Word{Meaning: "and, also, in addition", Furigana: "", Romaji: "soreto"}},
+	{level: 3, word: "釣", furigana: "つり", fixed: data.Word{Meaning: "fishing; change (money)", Furigana: "つり", Romaji: "tsuri"}},
+	{level: 3, word: "暖かい", furigana: "あたたか(い)", fixed: data.Word{Meaning: "warm, mild", Furigana: "あたたかい", Romaji: "atatakai"}},
+	{level: 3, word: "できれば", furigana: "", fixed: data.Word{Meaning: "if possible", Furigana: "", Romaji: "dekireba"}},
+	{level: 3, word: "ノー", furigana: "（no）", fixed: data.Word{Meaning: "no", Furigana: "", Romaji: "nō"}},
+	{level: 3, word: "税", furigana: "ぜい", fixed: data.Word{Meaning: "tax", Furigana: "ぜい", Romaji: "zei"}},
+	{level: 3, word: "できる", furigana: "（可能。出現。発生）", fixed: data.Word{Meaning: "to be able to, to be ready, to occur", Furigana: "", Romaji: "dekiru"}},
+	{level: 3, word: "うん", furigana: "（感）", fixed: data.Word{Meaning: "yes, yeah, uh-huh", Furigana: "", Romaji: "un"}},
+	{level: 3, word: "しまい", furigana: "（終わり）", fixed: data.Word{Meaning: "end, finish, close", Furigana: "", Romaji: "shimai"}},
+	{level: 3, word: "しまった", furigana: "（感）", fixed: data.Word{Meaning: "Damn it!", Furigana: "", Romaji: "shimatta"}},
+	{level: 3, word: "お目に掛かる", furigana: "おめにかかる", fixed: data.Word{Meaning: "to meet (humble)", Furigana: "おめにかかる", Romaji: "omenikakaru"}},
+	{level: 3, word: "大", furigana: "だい", fixed: data.Word{Meaning: "big, large, great", Furigana: "だい", Romaji: "dai"}},
+	{level: 3, word: "密", furigana: "みつ", fixed: data.Word{Meaning: "dense, close, secret", Furigana: "みつ", Romaji: "mitsu"}},
+	{level: 3, word: "よろしく", furigana: "（感）", fixed: data.Word{Meaning: "well, properly, suitably, best regards, please remember me", Furigana: "", Romaji: "yoroshiku"}},
+	{level: 3, word: "とん", furigana: "（1000", fixed: data.Word{Meaning: "ton", Furigana: "", Romaji: "ton"}},
+	{level: 3, word: "それ", furigana: "（接。感）", fixed: data.Word{Meaning: "it, that", Furigana: "", Romaji: "sore"}},
+	{level: 3, word: "しまう", furigana: "（終わる）", fixed: data.Word{Meaning: "to finish, to close, to do something completely", Furigana: "", Romaji: "shimau"}},
+	{level: 3, word: "はあ", furigana: "（感）", fixed: data.Word{Meaning: "yes, well, huh?", Furigana: "", Romaji: "hā"}},
+	{level: 3, word: "ふと", furigana: "（副）", fixed: data.Word{Meaning: "suddenly, casually, accidentally, incidentally, unexpectedly, unintentionally", Furigana: "", Romaji: "futo"}},
+	{level: 3, word: "番", furigana: "ばん", fixed: data.Word{Meaning: "number, turn, watch", Furigana: "ばん", Romaji: "ban"}},
+	{level: 3, word: "はい", furigana: "（感）", fixed: data.Word{Meaning: "yes, present (roll call)", Furigana: "", Romaji: "hai"}},
+	{level: 3, word: "急に", furigana: "きゅうに", fixed: data.Word{Meaning: "suddenly", Furigana: "きゅうに", Romaji: "kyūni"}},
+	{level: 3, word: "小", furigana: "しょう", fixed: data.Word{Meaning: "small, little", Furigana: "しょう", Romaji: "shō"}},
+	{level: 3, word: "すみません", furigana: "（感）", fixed: data.Word{Meaning: "sorry, excuse me", Furigana: "", Romaji: "sumimasen"}},
+	{level: 3, word: "どう", furigana: "（接。副）", fixed: data.Word{Meaning: "how, in what way, how about", Furigana: "", Romaji: "dō"}},
+	{level: 2, word: "先々週", furigana: "せんせんしゅう", fixed: data.Word{Meaning: "the week before last", Furigana: "せんせんしゅう", Romaji: "sensenshū"}},
+	{level: 2, word: "ずらり", furigana: "", fixed: data.Word{Meaning: "in a row, in a line", Furigana: "", Romaji: "zurari"}},
+	{level: 2, word: "ごぞんじですか", furigana: "", fixed: data.Word{Meaning: "do you know? (honorific)", Furigana: "", Romaji: "gozonjidesuka"}},
+	{level: 2, word: "思いっ切り", furigana: "おもいっきり", fixed: data.Word{Meaning: "with all one's strength, to one's heart's content", Furigana: "おもいっきり", Romaji: "omoikkiri"}},
+	{level: 2, word: "おきのどくに", furigana: "", fixed: data.Word{Meaning: "I am sorry to hear that", Furigana: "", Romaji: "okinodokuni"}},
+	{level: 2, word: "炒る", furigana: "いる", fixed: data.Word{Meaning: "to roast, to parch, to toast", Furigana: "いる", Romaji: "iru"}},
+	{level: 2, word: "あひら", furigana: "あひら", drop: true},
+	{level: 2, word: "しいんと", furigana: "（する）", fixed: data.Word{Meaning: "silent (as the grave), (deathly) quiet", Furigana: "", Romaji: "shiinto"}},
+	{level: 2, word: "初めに", furigana: "はじめに", fixed: data.Word{Meaning: "first, at the beginning", Furigana: "はじめに", Romaji: "hajimeni"}},
+	{level: 2, word: "通ずる", furigana: "つうずる", fixed: data.Word{Meaning: "to lead to, to be understood, to be versed in", Furigana: "つうずる", Romaji: "tsūzuru"}},
+	{level: 2, word: "おげんきで", furigana: "", fixed: data.Word{Meaning: "take care, stay well", Furigana: "", Romaji: "ogenkide"}},
+	{level: 2, word: "棄てる", furigana: "すてる", fixed: data.Word{Meaning: "to throw away, to abandon", Furigana: "すてる", Romaji: "suteru"}},
+	{level: 2, word: "ミリ", furigana: "（メートル）", fixed: data.Word{Meaning: "milli-, 10^-3", Furigana: "", Romaji: "miri"}},
+	{level: 2, word: "滑れる", furigana: "ずれる", fixed: data.Word{Meaning: "to slip off, to get out of place", Furigana: "ずれる", Romaji: "zureru"}},
+	{level: 2, word: "いってらっしゃい", furigana: "", fixed: data.Word{Meaning: "see you later, have a good day (to someone leaving)", Furigana: "", Romaji: "itterasshai"}},
+	{level: 2, word: "そのころ", furigana: "", fixed: data.Word{Meaning: "around that time, in those days", Furigana: "", Romaji: "sonokoro"}},
+	{level: 2, word: "だいいち", furigana: "（副）", fixed: data.Word{Meaning: "first, foremost, # 1", Furigana: "", Romaji: "daiichi"}},
+	{level: 2, word: "×", furigana: "ばつ", fixed: data.Word{Meaning: "cross, X mark (wrong)", Furigana: "ばつ", Romaji: "batsu"}},
+	{level: 2, word: "関西", furigana: "かんさい", fixed: data.Word{Meaning: "Kansai region (Osaka, Kyoto, Kobe area)", Furigana: "かんさい", Romaji: "kansai"}},
+	{level: 2, word: "オーバーコート", furigana: "", fixed: data.Word{Meaning: "overcoat", Furigana: "", Romaji: "ōbākōto"}},
+	{level: 2, word: "しょうがない", furigana: "", fixed: data.Word{Meaning: "it can't be helped", Furigana: "", Romaji: "shōganai"}},
+	{level: 2, word: "せっせと", furigana: "", fixed: data.Word{Meaning: "diligently, busily", Furigana: "", Romaji: "sesseto"}},
+	{level: 2, word: "そうっと", furigana: "", fixed: data.Word{Meaning: "softly, quietly, gently", Furigana: "", Romaji: "sōtto"}},
+	{level: 2, word: "それなのに", furigana: "", fixed: data.Word{Meaning: "and yet, despite that", Furigana: "", Romaji: "sorenanoni"}},
+	{level: 2, word: "いっていらっしゃい", furigana: "", fixed: data.Word{Meaning: "see you later, have a good day (to someone leaving)", Furigana: "", Romaji: "itteirasshai"}},
+	{level: 2, word: "ぴたり", furigana: "", fixed: data.Word{Meaning: "exactly, tightly, (stopping) suddenly", Furigana: "", Romaji: "pitari"}},
+	{level: 2, word: "清む", furigana: "すむ", fixed: data.Word{Meaning: "to become clear (water)", Furigana: "すむ", Romaji: "sumu"}},
+	{level: 2, word: "転々", furigana: "てんてん", fixed: data.Word{Meaning: "moving from place to place, rolling about", Furigana: "てんてん", Romaji: "tenten"}},
+	{level: 2, word: "おかけください", furigana: "", fixed: data.Word{Meaning: "please sit down", Furigana: "", Romaji: "okakekudasai"}},
+	{level: 2, word: "バイバイ", furigana: "", fixed: data.Word{Meaning: "bye-bye", Furigana: "", Romaji: "baibai"}},
+	{level: 2, word: "じゅうたん", furigana: "（カーペット）", fixed: data.Word{Meaning: "carpet", Furigana: "", Romaji: "jūtan"}},
+	{level: 2, word: "破く", furigana: "やぶく", fixed: data.Word{Meaning: "to tear, to rip", Furigana: "やぶく", Romaji: "yabuku"}},
+	{level: 2, word: "にこにこ", furigana: "", fixed: data.Word{Meaning: "smiling, with a smile", Furigana: "", Romaji: "nikoniko"}},
+	{level: 2, word: "お出掛け", furigana: "おでかけ", fixed: data.Word{Meaning: "going out, outing", Furigana: "おでかけ", Romaji: "odekake"}},
+	{level: 2, word: "存ずる", furigana: "ぞんずる", fixed: data.Word{Meaning: "to think, to know (humble)", Furigana: "ぞんずる", Romaji: "zonzuru"}},
+	{level: 2, word: "いってまいります", furigana: "", fixed: data.Word{Meaning: "I'm off, see you later (when leaving)", Furigana: "", Romaji: "ittemairimasu"}},
+	{level: 2, word: "咥える", furigana: "くわえる", fixed: data.Word{Meaning: "to hold in the mouth", Furigana: "くわえる", Romaji: "kuwaeru"}},
+	{level: 2, word: "ずうっと", furigana: "", fixed: data.Word{Meaning: "all the time, all the way, by far", Furigana: "", Romaji: "zūtto"}},
+	{level: 2, word: "茶色い", furigana: "ちゃいろい", fixed: data.Word{Meaning: "brown", Furigana: "ちゃいろい", Romaji: "chairoi"}},
+	{level: 2, word: "斜", furigana: "はす", fixed: data.Word{Meaning: "diagonal, oblique", Furigana: "はす", Romaji: "hasu"}},
+	{level: 2, word: "慶ぶ", furigana: "よろこぶ", fixed: data.Word{Meaning: "to be delighted, to rejoice", Furigana: "よろこぶ", Romaji: "yorokobu"}},
+	{level: 2, word: "傾らか", furigana: "なだらか", fixed: data.Word{Meaning: "gently sloping, smooth", Furigana: "なだらか", Romaji: "nadaraka"}},
+}
+
+// migrateWordFixes brings the JLPT words seeded before the vocabulary was
+// cleaned up in line with it. Words are matched on their original reading,
+// so rows changed since are left alone.
+func migrateWordFixes(tx *sql.Tx) error {
+	for _, f := range wordFixes {
+		var err error
+		if f.drop {
+			_, err = tx.Exec(`
+				DELETE FROM word_progress WHERE word_id IN (
+					SELECT id FROM words WHERE level = ? AND word = ? AND furigana = ?
+				)`, f.level, f.word, f.furigana)
+			if err == nil {
+				_, err = tx.Exec(`DELETE FROM words WHERE level = ? AND word = ? AND furigana = ?`,
+					f.level, f.word, f.furigana)
+			}
+		} else {
+			_, err = tx.Exec(`
+				UPDATE words SET meaning = ?, furigana = ?, romaji = ?
+				WHERE level = ? AND word = ? AND furigana = ?`,
+				f.fixed.Meaning, f.fixed.Furigana, f.fixed.Romaji, f.level, f.word, f.furigana)
+		}
+		if err != nil {
+			return fmt.Errorf("error fixing word %s: %s", f.word, err)
+		}
+	}
+	return nil
+}
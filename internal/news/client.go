@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -52,6 +53,14 @@ type NewsDetail struct {
 	Paragraphs  []Paragraph `json:"paragraphs"`
 }
 
+type Sentence struct {
+	NewsID   int    `json:"news_id"`
+	NhkID    string `json:"nhk_id"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+	RawText  string `json:"raw_text"`
+}
+
 func (c *Client) GetNewsList(limit, offset int) ([]NewsListItem, error) {
 	url := fmt.Sprintf("%s/api/v1/news?limit=%d&offset=%d", c.baseURL, limit, offset)
 
@@ -94,6 +103,29 @@ func (c *Client) GetNewsDetail(id int) (*NewsDetail, error) {
 	return &detail, nil
 }
 
+// GetSentences returns paragraphs from the news corpus that contain a token
+// with the given base form.
+func (c *Client) GetSentences(baseForm string, limit int) ([]Sentence, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/sentences?base_form=%s&limit=%d", c.baseURL, url.QueryEscape(baseForm), limit)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sentences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var sentences []Sentence
+	if err := json.NewDecoder(resp.Body).Decode(&sentences); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return sentences, nil
+}
+
 func (c *Client) IsAvailable() bool {
 	url := fmt.Sprintf("%s/health", c.baseURL)
 	resp, err := c.httpClient.Get(url)
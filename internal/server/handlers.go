@@ -76,6 +76,32 @@ func (s *Server) handleGetNewsById(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, news)
 }
 
+func (s *Server) handleGetSentences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	baseForm := r.URL.Query().Get("base_form")
+	if baseForm == "" {
+		writeError(w, http.StatusBadRequest, "Missing base_form")
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 3)
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > 20 {
+		limit = 20
+	}
+
+	sentences, err := s.store.GetSentences(ctx, baseForm, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch sentences")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sentences)
+}
+
 func parseIntParam(r *http.Request, key string, defaultVal int) int {
 	str := r.URL.Query().Get(key)
 	if str == "" {
@@ -297,3 +297,81 @@ func TestHandleGetNewsById(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleGetSentences(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		setupMock  func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantLen    int
+	}{
+		{
+			name:  "default limit",
+			query: "?base_form=%E9%9B%A8",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"news_id", "nhk_id", "title", "position", "raw_text"}).
+					AddRow(1, "ne123", "Test News", 0, "雨が降りました。")
+				mock.ExpectQuery("SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text FROM paragraphs p").
+					WithArgs("雨", 3).
+					WillReturnRows(rows)
+			},
+			wantStatus: http.StatusOK,
+			wantLen:    1,
+		},
+		{
+			name:  "limit clamped to 20",
+			query: "?base_form=%E9%9B%A8&limit=50",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"news_id", "nhk_id", "title", "position", "raw_text"})
+				mock.ExpectQuery("SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text FROM paragraphs p").
+					WithArgs("雨", 20).
+					WillReturnRows(rows)
+			},
+			wantStatus: http.StatusOK,
+			wantLen:    0,
+		},
+		{
+			name:       "missing base form returns bad request",
+			query:      "",
+			setupMock:  func(mock sqlmock.Sqlmock) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "database error returns 500",
+			query: "?base_form=%E9%9B%A8",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text FROM paragraphs p").
+					WithArgs("雨", 3).
+					WillReturnError(context.DeadlineExceeded)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			s := store.NewWithDB(db)
+			server := New(s)
+
+			req := httptest.NewRequest("GET", "/api/v1/sentences"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			server.handleGetSentences(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var response []store.Sentence
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response, tt.wantLen)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -26,6 +26,7 @@ func (s *Server) Router() http.Handler {
 	// API v1 routes (with rate limiting)
 	mux.Handle("GET /api/v1/news", rateLimiter.Middleware(http.HandlerFunc(s.handleGetNews)))
 	mux.Handle("GET /api/v1/news/{id}", rateLimiter.Middleware(http.HandlerFunc(s.handleGetNewsById)))
+	mux.Handle("GET /api/v1/sentences", rateLimiter.Middleware(http.HandlerFunc(s.handleGetSentences)))
 
 	// CORS middleware
 	return corsMiddleware(mux)
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"time"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+)
+
+// AnkiReviewer is the part of the Anki client used to replay offline
+// answers.
+type AnkiReviewer interface {
+	GetCardsInfo(cardIDs []int64) ([]*anki.CardInfo, error)
+	AnswerCards(answers []anki.Answer) ([]bool, error)
+}
+
+// ReplayResult counts the offline answers sent to Anki, and those dropped
+// because the card changed or disappeared in the meantime.
+type ReplayResult struct {
+	Replayed int
+	Skipped  int
+}
+
+type AnkiCacheService interface {
+	// CacheCards keeps the due cards of the search for reviewing offline.
+	CacheCards(source string, cards []*anki.CardInfo) error
+	// CachedCards returns the cached cards not answered offline yet.
+	CachedCards(source string) ([]data.AnkiCard, error)
+	// CardAnswered drops a card answered in Anki from the cache.
+	CardAnswered(cardID int64) error
+	// AnswerOffline records an answer to send once Anki is back.
+	AnswerOffline(card data.AnkiCard, ease int) error
+	PendingAnswers() (int, error)
+	// PendingCardIDs returns the cards answered offline, which stay due in
+	// Anki until replayed.
+	PendingCardIDs() ([]int64, error)
+	// Replay sends the offline answers to Anki, skipping cards modified
+	// since they were cached.
+	Replay(client AnkiReviewer) (ReplayResult, error)
+}
+
+type ankiCacheService struct {
+	repo *db.DB
+	now  func() time.Time
+}
+
+func NewAnkiCacheService(db *db.DB) AnkiCacheService {
+	return &ankiCacheService{
+		repo: db,
+		now:  time.Now,
+	}
+}
+
+func (s *ankiCacheService) CacheCards(source string, cards []*anki.CardInfo) error {
+	cached := make([]data.AnkiCard, len(cards))
+	for i, c := range cards {
+		cached[i] = data.AnkiCard{
+			CardID:   c.CardID,
+			Source:   source,
+			Deck:     c.DeckName,
+			Question: c.Question,
+			Reading:  c.Reading,
+			Answer:   c.Answer,
+			Mod:      c.Mod,
+		}
+	}
+	return s.repo.CacheAnkiCards(source, cached, s.now())
+}
+
+func (s *ankiCacheService) CachedCards(source string) ([]data.AnkiCard, error) {
+	return s.repo.GetCachedAnkiCards(source)
+}
+
+func (s *ankiCacheService) CardAnswered(cardID int64) error {
+	return s.repo.DeleteCachedAnkiCard(cardID)
+}
+
+func (s *ankiCacheService) AnswerOffline(card data.AnkiCard, ease int) error {
+	return s.repo.SavePendingAnkiAnswer(data.PendingAnkiAnswer{
+		CardID:     card.CardID,
+		Ease:       ease,
+		Mod:        card.Mod,
+		AnsweredAt: s.now(),
+	})
+}
+
+func (s *ankiCacheService) PendingAnswers() (int, error) {
+	return s.repo.CountPendingAnkiAnswers()
+}
+
+func (s *ankiCacheService) PendingCardIDs() ([]int64, error) {
+	pending, err := s.repo.GetPendingAnkiAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(pending))
+	for i, a := range pending {
+		ids[i] = a.CardID
+	}
+	return ids, nil
+}
+
+func (s *ankiCacheService) Replay(client AnkiReviewer) (ReplayResult, error) {
+	var result ReplayResult
+
+	pending, err := s.repo.GetPendingAnkiAnswers()
+	if err != nil || len(pending) == 0 {
+		return result, err
+	}
+
+	ids := make([]int64, len(pending))
+	for i, a := range pending {
+		ids[i] = a.CardID
+	}
+	cards, err := client.GetCardsInfo(ids)
+	if err != nil {
+		return result, err
+	}
+	mods := make(map[int64]int64, len(cards))
+	for _, c := range cards {
+		mods[c.CardID] = c.Mod
+	}
+
+	// A card reviewed elsewhere, edited or deleted since it was cached is
+	// not answered again.
+	var toSend []data.PendingAnkiAnswer
+	for _, a := range pending {
+		if mod, ok := mods[a.CardID]; ok && mod == a.Mod {
+			toSend = append(toSend, a)
+			continue
+		}
+		if err := s.repo.DeletePendingAnkiAnswer(a); err != nil {
+			return result, err
+		}
+		result.Skipped++
+	}
+
+	if len(toSend) == 0 {
+		return result, nil
+	}
+
+	answers := make([]anki.Answer, len(toSend))
+	for i, a := range toSend {
+		answers[i] = anki.Answer{CardID: a.CardID, Ease: a.Ease}
+	}
+	answered, err := client.AnswerCards(answers)
+	if err != nil {
+		return result, err
+	}
+
+	for i, a := range toSend {
+		if err := s.repo.DeletePendingAnkiAnswer(a); err != nil {
+			return result, err
+		}
+		if i < len(answered) && answered[i] {
+			result.Replayed++
+		} else {
+			result.Skipped++
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAnkiReviewer struct {
+	cards    []*anki.CardInfo
+	answered []anki.Answer
+}
+
+func (f *fakeAnkiReviewer) GetCardsInfo(cardIDs []int64) ([]*anki.CardInfo, error) {
+	return f.cards, nil
+}
+
+func (f *fakeAnkiReviewer) AnswerCards(answers []anki.Answer) ([]bool, error) {
+	f.answered = append(f.answered, answers...)
+	result := make([]bool, len(answers))
+	for i := range result {
+		result[i] = true
+	}
+	return result, nil
+}
+
+func setupAnkiCacheService(t *testing.T) AnkiCacheService {
+	database, err := db.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate())
+	t.Cleanup(func() { database.Close() })
+
+	return NewAnkiCacheService(database)
+}
+
+func TestAnkiCacheOfflineReview(t *testing.T) {
+	svc := setupAnkiCacheService(t)
+
+	due := []*anki.CardInfo{
+		{CardID: 1, DeckName: "Japanese::N5", Question: "雨", Reading: "あめ", Answer: "rain", Mod: 100},
+		{CardID: 2, DeckName: "Japanese::N4", Question: "雪", Reading: "ゆき", Answer: "snow", Mod: 200},
+	}
+	require.NoError(t, svc.CacheCards(`deck:"Japanese"`, due))
+
+	cards, err := svc.CachedCards(`deck:"Japanese"`)
+	require.NoError(t, err)
+	require.Len(t, cards, 2)
+	assert.Equal(t, "雨", cards[0].Question)
+	assert.Equal(t, "Japanese::N5", cards[0].Deck, "cards keep their deck apart from the search")
+
+	require.NoError(t, svc.AnswerOffline(cards[0], 3))
+
+	// Answered cards are not shown again while offline.
+	cards, err = svc.CachedCards(`deck:"Japanese"`)
+	require.NoError(t, err)
+	require.Len(t, cards, 1)
+	assert.Equal(t, int64(2), cards[0].CardID)
+
+	pending, err := svc.PendingAnswers()
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+}
+
+func TestAnkiCacheReplaySkipsChangedCards(t *testing.T) {
+	svc := setupAnkiCacheService(t)
+
+	require.NoError(t, svc.CacheCards("Japanese", []*anki.CardInfo{
+		{CardID: 1, Question: "雨", Mod: 100},
+		{CardID: 2, Question: "雪", Mod: 200},
+		{CardID: 3, Question: "風", Mod: 300},
+	}))
+	cards, err := svc.CachedCards("Japanese")
+	require.NoError(t, err)
+	for _, c := range cards {
+		require.NoError(t, svc.AnswerOffline(c, 3))
+	}
+
+	// Card 2 was reviewed on another device and card 3 was deleted.
+	reviewer := &fakeAnkiReviewer{cards: []*anki.CardInfo{
+		{CardID: 1, Mod: 100},
+		{CardID: 2, Mod: 250},
+	}}
+
+	result, err := svc.Replay(reviewer)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Replayed: 1, Skipped: 2}, result)
+	assert.Equal(t, []anki.Answer{{CardID: 1, Ease: 3}}, reviewer.answered)
+
+	pending, err := svc.PendingAnswers()
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+
+	cards, err = svc.CachedCards("Japanese")
+	require.NoError(t, err)
+	assert.Empty(t, cards)
+}
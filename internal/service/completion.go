@@ -0,0 +1,90 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+// CompletionPolicy decides what happens once every word of a JLPT level has
+// been introduced.
+type CompletionPolicy string
+
+const (
+	// PolicyAdvance adds the next, harder level to the selection and keeps
+	// reviewing the completed one.
+	PolicyAdvance CompletionPolicy = "advance"
+	// PolicyReset introduces the words of the level again, keeping their
+	// review schedule.
+	PolicyReset CompletionPolicy = "reset"
+	// PolicyStop only reports the completion.
+	PolicyStop CompletionPolicy = "stop"
+)
+
+type LevelCompletion struct {
+	// Completed holds the selected levels with no unseen words left. Levels
+	// reset by PolicyReset are included.
+	Completed []int
+	// New holds the completed levels not reported by an earlier call, even
+	// one made before a restart.
+	New []int
+	// Levels is the level selection after applying the policy.
+	Levels []int
+}
+
+// CompleteLevels detects completed levels among the selection and applies the
+// policy to them.
+func (s *service) CompleteLevels(levels []int, policy CompletionPolicy) (LevelCompletion, error) {
+	progress, err := s.repo.GetLevelProgress(s.now())
+	if err != nil {
+		return LevelCompletion{}, err
+	}
+
+	reported, err := s.repo.GetCompletedLevels()
+	if err != nil {
+		return LevelCompletion{}, err
+	}
+
+	result := LevelCompletion{Levels: slices.Clone(levels)}
+	for _, p := range progress {
+		if !slices.Contains(levels, p.Level) {
+			continue
+		}
+		if !isComplete(p) {
+			// New words or a reset make the level incomplete again, so its
+			// next completion is reported too.
+			if slices.Contains(reported, p.Level) {
+				if err := s.repo.DeleteCompletedLevel(p.Level); err != nil {
+					return LevelCompletion{}, err
+				}
+			}
+			continue
+		}
+		result.Completed = append(result.Completed, p.Level)
+		if !slices.Contains(reported, p.Level) {
+			result.New = append(result.New, p.Level)
+		}
+
+		switch policy {
+		case PolicyReset:
+			if err := s.repo.ResetSeenWords(p.Level); err != nil {
+				return LevelCompletion{}, err
+			}
+			continue
+		case PolicyAdvance:
+			next := p.Level - 1
+			if next >= 1 && !slices.Contains(result.Levels, next) {
+				result.Levels = append(result.Levels, next)
+			}
+		}
+		if err := s.repo.SaveCompletedLevel(p.Level, s.now()); err != nil {
+			return LevelCompletion{}, err
+		}
+	}
+
+	return result, nil
+}
+
+func isComplete(p data.LevelProgress) bool {
+	return p.Total > 0 && p.Seen == p.Total
+}
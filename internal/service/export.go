@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+)
+
+// exportBatchSize is how many notes are sent to AnkiConnect per request.
+const exportBatchSize = 500
+
+// Tags of exported words studied in keiko.
+const (
+	TagLearning = "keiko::learning"
+	TagLearned  = "keiko::learned"
+)
+
+// AnkiExporter is the part of the Anki client used to export words.
+type AnkiExporter interface {
+	CreateDeck(name string) error
+	GetNoteTypeFields(noteType string) ([]string, error)
+	VocabNotes(deck, noteType string, fields []string, words []anki.VocabNote) ([]anki.Note, error)
+	CanAddNotes(notes []anki.Note) ([]bool, error)
+	AddNotes(notes []anki.Note) ([]int64, error)
+}
+
+// ExportResult counts the notes added to Anki, those skipped because the
+// deck already has them, and the words left out because an earlier word of
+// the export has the same first field, which Anki would reject as a
+// duplicate.
+type ExportResult struct {
+	Added      int
+	Skipped    int
+	Duplicates int
+}
+
+type ExportService interface {
+	// ExportToAnki adds the words of the levels to the deck, tagged with
+	// their JLPT level and how far they were studied.
+	ExportToAnki(client AnkiExporter, deck, noteType string, levels []int) (ExportResult, error)
+}
+
+type exportService struct {
+	repo *db.DB
+}
+
+func NewExportService(db *db.DB) ExportService {
+	return &exportService{repo: db}
+}
+
+func (s *exportService) ExportToAnki(client AnkiExporter, deck, noteType string, levels []int) (ExportResult, error) {
+	var result ExportResult
+
+	words, err := s.repo.GetWordsWithProgress(levels)
+	if err != nil || len(words) == 0 {
+		return result, err
+	}
+
+	if err := client.CreateDeck(deck); err != nil {
+		return result, fmt.Errorf("error creating anki deck: %s", err)
+	}
+
+	vocab := make([]anki.VocabNote, len(words))
+	for i, w := range words {
+		vocab[i] = anki.VocabNote{Word: w.Word.Word, Reading: w.Furigana, Meaning: w.Meaning}
+	}
+	fields, err := client.GetNoteTypeFields(noteType)
+	if err != nil {
+		return result, err
+	}
+	notes, err := client.VocabNotes(deck, noteType, fields, vocab)
+	if err != nil {
+		return result, err
+	}
+
+	// Anki checks duplicates on the first field, and only against notes
+	// already in the collection, so the export keeps the first of each.
+	exported := make(map[string]bool, len(notes))
+	unique := notes[:0]
+	for i, n := range notes {
+		key := n.Fields[fields[0]]
+		if exported[key] {
+			result.Duplicates++
+			continue
+		}
+		exported[key] = true
+		n.Tags = append(n.Tags, exportTags(words[i])...)
+		unique = append(unique, n)
+	}
+	notes = unique
+
+	for start := 0; start < len(notes); start += exportBatchSize {
+		batch := notes[start:min(start+exportBatchSize, len(notes))]
+
+		// Notes already in the deck are skipped instead of failing the
+		// whole batch.
+		ok, err := client.CanAddNotes(batch)
+		if err != nil {
+			return result, err
+		}
+		var toAdd []anki.Note
+		for i, n := range batch {
+			if i < len(ok) && ok[i] {
+				toAdd = append(toAdd, n)
+			}
+		}
+		result.Skipped += len(batch) - len(toAdd)
+		if len(toAdd) == 0 {
+			continue
+		}
+
+		ids, err := client.AddNotes(toAdd)
+		if err != nil {
+			return result, err
+		}
+		for _, id := range ids {
+			if id != 0 {
+				result.Added++
+			} else {
+				result.Skipped++
+			}
+		}
+	}
+	return result, nil
+}
+
+// exportTags are the JLPT level of the word and, once studied, its progress.
+func exportTags(w data.WordProgress) []string {
+	tags := []string{fmt.Sprintf("jlpt::n%d", w.Level)}
+	switch {
+	case w.Interval >= data.LearnedInterval:
+		tags = append(tags, TagLearned)
+	case w.Seen || w.Interval > 0:
+		tags = append(tags, TagLearning)
+	}
+	return tags
+}
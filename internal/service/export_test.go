@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAnkiExporter keeps the added notes, rejecting words already added.
+type fakeAnkiExporter struct {
+	decks        []string
+	notes        []anki.Note
+	fieldLookups int
+}
+
+func (f *fakeAnkiExporter) CreateDeck(name string) error {
+	f.decks = append(f.decks, name)
+	return nil
+}
+
+func (f *fakeAnkiExporter) GetNoteTypeFields(noteType string) ([]string, error) {
+	f.fieldLookups++
+	return []string{"Front", "Back"}, nil
+}
+
+func (f *fakeAnkiExporter) VocabNotes(deck, noteType string, fields []string, words []anki.VocabNote) ([]anki.Note, error) {
+	notes := make([]anki.Note, len(words))
+	for i, w := range words {
+		notes[i] = anki.Note{
+			Deck:     deck,
+			NoteType: noteType,
+			Fields:   map[string]string{fields[0]: w.Word, fields[1]: w.Meaning},
+			Tags:     []string{anki.NoteTag},
+		}
+	}
+	return notes, nil
+}
+
+func (f *fakeAnkiExporter) CanAddNotes(notes []anki.Note) ([]bool, error) {
+	ok := make([]bool, len(notes))
+	for i, n := range notes {
+		ok[i] = true
+		for _, added := range f.notes {
+			if added.Fields["Front"] == n.Fields["Front"] {
+				ok[i] = false
+			}
+		}
+	}
+	return ok, nil
+}
+
+func (f *fakeAnkiExporter) AddNotes(notes []anki.Note) ([]int64, error) {
+	ids := make([]int64, len(notes))
+	for i, n := range notes {
+		for _, added := range f.notes {
+			if added.Fields["Front"] == n.Fields["Front"] {
+				return nil, fmt.Errorf("cannot create note because it is a duplicate")
+			}
+		}
+		f.notes = append(f.notes, n)
+		ids[i] = int64(len(f.notes))
+	}
+	return ids, nil
+}
+
+func TestExportToAnki(t *testing.T) {
+	database, err := db.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate())
+	t.Cleanup(func() { database.Close() })
+
+	require.NoError(t, database.SeedVocab([]data.Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Level: 5},
+		{Word: "猫", Meaning: "cat", Furigana: "ねこ", Level: 5},
+		{Word: "食べる", Meaning: "to eat", Furigana: "たべる", Level: 4},
+		{Word: "経済", Meaning: "economy", Furigana: "けいざい", Level: 2},
+	}))
+	_, err = database.Exec(`UPDATE words SET seen = 1 WHERE word IN ('犬', '猫')`)
+	require.NoError(t, err)
+	_, err = database.Exec(`INSERT INTO word_progress (word_id, due_at, interval_days, ease, reps, lapses, introduced_at, reviewed_at)
+		SELECT id, 0, 30, 2.5, 5, 0, 0, 0 FROM words WHERE word = '犬'`)
+	require.NoError(t, err)
+
+	client := &fakeAnkiExporter{}
+	svc := NewExportService(database)
+
+	result, err := svc.ExportToAnki(client, "JLPT", "Basic", []int{5, 4})
+	require.NoError(t, err)
+	assert.Equal(t, ExportResult{Added: 3}, result)
+	assert.Equal(t, []string{"JLPT"}, client.decks)
+	assert.Equal(t, 1, client.fieldLookups, "the note type fields are fetched once")
+
+	require.Len(t, client.notes, 3)
+	assert.Equal(t, []string{anki.NoteTag, "jlpt::n5", TagLearned}, client.notes[0].Tags)
+	assert.Equal(t, []string{anki.NoteTag, "jlpt::n5", TagLearning}, client.notes[1].Tags)
+	assert.Equal(t, []string{anki.NoteTag, "jlpt::n4"}, client.notes[2].Tags)
+
+	// Exporting again skips the notes already there.
+	result, err = svc.ExportToAnki(client, "JLPT", "Basic", []int{5, 4, 2})
+	require.NoError(t, err)
+	assert.Equal(t, ExportResult{Added: 1, Skipped: 3}, result)
+}
+
+func TestExportToAnkiLeavesOutDuplicates(t *testing.T) {
+	database, err := db.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate())
+	t.Cleanup(func() { database.Close() })
+
+	require.NoError(t, database.SeedVocab([]data.Word{
+		{Word: "空", Meaning: "sky", Furigana: "そら", Level: 5},
+		{Word: "空", Meaning: "empty", Furigana: "から", Level: 5},
+		{Word: "猫", Meaning: "cat", Furigana: "ねこ", Level: 5},
+	}))
+
+	client := &fakeAnkiExporter{}
+	result, err := NewExportService(database).ExportToAnki(client, "JLPT", "Basic", []int{5})
+	require.NoError(t, err)
+	assert.Equal(t, ExportResult{Added: 2, Duplicates: 1}, result)
+
+	require.Len(t, client.notes, 2)
+	assert.Equal(t, "sky", client.notes[0].Fields["Back"])
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/srs"
+)
+
+const DefaultNewKanjiPerDay = 5
+
+var (
+	// ErrorNoKanjiDue means nothing is due and the daily new kanji limit is
+	// reached.
+	ErrorNoKanjiDue = errors.New("no kanji due")
+	// ErrorNoKanjiFound means nothing is due and every kanji of the selected
+	// levels has been seen.
+	ErrorNoKanjiFound = db.ErrorNoKanjiFound
+)
+
+// GetNextKanji works like GetNextWord: the most overdue kanji first, then a
+// new one while the daily kanji limit allows it, and skipped kanji last.
+func (s *service) GetNextKanji(levels []int) (data.Kanji, error) {
+	now := s.now()
+
+	skipped := activeSkips(s.skippedKanji, now)
+	kanji, err := s.nextKanji(levels, now, skipped)
+	if len(skipped) > 0 && (errors.Is(err, ErrorNoKanjiDue) || errors.Is(err, ErrorNoKanjiFound)) {
+		clear(s.skippedKanji)
+		return s.nextKanji(levels, now, nil)
+	}
+	return kanji, err
+}
+
+func (s *service) nextKanji(levels []int, now time.Time, exclude []int) (data.Kanji, error) {
+	kanji, err := s.repo.GetDueKanji(levels, now, exclude)
+	if err == nil {
+		return kanji, nil
+	}
+	if !errors.Is(err, db.ErrorNoKanjiFound) {
+		return data.Kanji{}, err
+	}
+
+	introduced, err := s.repo.CountKanjiIntroducedSince(startOfDay(now))
+	if err != nil {
+		return data.Kanji{}, err
+	}
+	if introduced >= s.newKanjiPerDay {
+		return data.Kanji{}, ErrorNoKanjiDue
+	}
+
+	return s.repo.GetNextKanji(levels, exclude)
+}
+
+// SkipKanji puts the kanji aside for SkipDelay without grading it.
+func (s *service) SkipKanji(id int) {
+	s.skippedKanji[id] = s.now().Add(SkipDelay)
+}
+
+func (s *service) ReviewKanji(id int, grade srs.Grade) error {
+	now := s.now()
+
+	state, err := s.repo.GetKanjiProgress(id, now)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveKanjiProgress(id, srs.Schedule(state, grade, now), now); err != nil {
+		return err
+	}
+
+	delete(s.skippedKanji, id)
+	return s.repo.MarkKanjiAsSeen(id)
+}
+
+func (s *service) SetNewKanjiPerDay(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.newKanjiPerDay = n
+}
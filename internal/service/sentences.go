@@ -0,0 +1,97 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/news"
+)
+
+const (
+	// SentenceCacheTTL is how long cached sentences are used before asking the
+	// news server again, as the corpus grows every day.
+	SentenceCacheTTL = 7 * 24 * time.Hour
+	sentencesPerWord = 5
+)
+
+// SentenceFetcher is the part of the news client used to look up sentences.
+type SentenceFetcher interface {
+	GetSentences(baseForm string, limit int) ([]news.Sentence, error)
+}
+
+type SentenceService interface {
+	// GetSentences returns example sentences for the word, asking the news
+	// server when the cache is missing or stale.
+	GetSentences(word string) ([]data.Sentence, error)
+	// CachedSentences only reads the cache and never blocks on the network.
+	CachedSentences(word string) ([]data.Sentence, error)
+}
+
+type sentenceService struct {
+	repo   *db.DB
+	client SentenceFetcher
+	now    func() time.Time
+}
+
+func NewSentenceService(db *db.DB, client SentenceFetcher) SentenceService {
+	return &sentenceService{
+		repo:   db,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (s *sentenceService) GetSentences(word string) ([]data.Sentence, error) {
+	now := s.now()
+
+	fetchedAt, cached, err := s.repo.GetSentenceLookup(word)
+	if err != nil {
+		return nil, err
+	}
+	if cached && now.Sub(fetchedAt) < SentenceCacheTTL {
+		return s.repo.GetSentences(word)
+	}
+
+	found, err := s.client.GetSentences(word, sentencesPerWord)
+	if err != nil {
+		// Fall back to stale sentences while the server is unreachable.
+		if cached {
+			log.Printf("sentence lookup failed, using cache: %v", err)
+			return s.repo.GetSentences(word)
+		}
+		return nil, err
+	}
+
+	sentences := make([]data.Sentence, 0, len(found))
+	for _, f := range found {
+		sentences = append(sentences, data.Sentence{
+			Word:  word,
+			NhkID: f.NhkID,
+			Title: f.Title,
+			Text:  exampleSentence(f.RawText, word),
+		})
+	}
+
+	if err := s.repo.SaveSentences(word, sentences, now); err != nil {
+		return nil, err
+	}
+	return sentences, nil
+}
+
+// exampleSentence cuts the sentence containing word out of a paragraph. When
+// the word only appears inflected, the whole paragraph is kept.
+func exampleSentence(paragraph, word string) string {
+	for _, sentence := range strings.SplitAfter(paragraph, "。") {
+		if strings.Contains(sentence, word) {
+			return strings.TrimSpace(sentence)
+		}
+	}
+	return paragraph
+}
+
+func (s *sentenceService) CachedSentences(word string) ([]data.Sentence, error) {
+	return s.repo.GetSentences(word)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/news"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSentenceFetcher struct {
+	sentences []news.Sentence
+	err       error
+	calls     int
+}
+
+func (f *fakeSentenceFetcher) GetSentences(baseForm string, limit int) ([]news.Sentence, error) {
+	f.calls++
+	return f.sentences, f.err
+}
+
+func setupSentenceService(t *testing.T, fetcher *fakeSentenceFetcher) (*sentenceService, *db.DB) {
+	database, err := db.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate())
+
+	svc := NewSentenceService(database, fetcher).(*sentenceService)
+	return svc, database
+}
+
+func TestSentenceServiceGetSentences(t *testing.T) {
+	fetcher := &fakeSentenceFetcher{
+		sentences: []news.Sentence{{NhkID: "ne1", Title: "天気", RawText: "雨が降りました。"}},
+	}
+	svc, database := setupSentenceService(t, fetcher)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	sentences, err := svc.GetSentences("雨")
+	require.NoError(t, err)
+	require.Len(t, sentences, 1)
+	assert.Equal(t, "雨が降りました。", sentences[0].Text)
+
+	// Served from the cache until it expires.
+	_, err = svc.GetSentences("雨")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetcher.calls)
+
+	cached, err := svc.CachedSentences("雨")
+	require.NoError(t, err)
+	assert.Equal(t, sentences, cached)
+
+	svc.now = func() time.Time { return now.Add(SentenceCacheTTL) }
+	_, err = svc.GetSentences("雨")
+	require.NoError(t, err)
+	assert.Equal(t, 2, fetcher.calls)
+}
+
+func TestSentenceServiceFallsBackToStaleCache(t *testing.T) {
+	fetcher := &fakeSentenceFetcher{
+		sentences: []news.Sentence{{NhkID: "ne1", Title: "天気", RawText: "雨が降りました。"}},
+	}
+	svc, database := setupSentenceService(t, fetcher)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.GetSentences("雨")
+	require.NoError(t, err)
+
+	fetcher.err = errors.New("connection refused")
+	svc.now = func() time.Time { return now.Add(2 * SentenceCacheTTL) }
+
+	sentences, err := svc.GetSentences("雨")
+	require.NoError(t, err)
+	assert.Len(t, sentences, 1)
+
+	_, err = svc.GetSentences("経済")
+	assert.Error(t, err)
+}
+
+func TestExampleSentence(t *testing.T) {
+	paragraph := "今日は晴れました。明日は雨が降りそうです。傘を持ってください。"
+
+	assert.Equal(t, "明日は雨が降りそうです。", exampleSentence(paragraph, "雨"))
+	assert.Equal(t, paragraph, exampleSentence(paragraph, "降る"))
+}
@@ -1,36 +1,151 @@
 package service
 
 import (
+	"errors"
+	"slices"
+	"time"
+
 	"github.com/LealKevin/keiko/internal/data"
 	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/srs"
+)
+
+const DefaultNewWordsPerDay = 20
+
+// SkipDelay is how long a skipped word or kanji stays out of the way, unless
+// nothing else is left to show.
+const SkipDelay = 10 * time.Minute
+
+var (
+	// ErrorNoWordsDue means nothing is due and the daily new word limit is
+	// reached.
+	ErrorNoWordsDue = errors.New("no words due")
+	// ErrorNoWordsFound means nothing is due and every word of the selection
+	// has been seen.
+	ErrorNoWordsFound = db.ErrorNoWordsFound
 )
 
 type VocabService interface {
-	GetNextWord(levels []int) (data.Word, error)
+	GetNextWord(levels []int, decks ...string) (data.Word, error)
+	ReviewWord(id int, grade srs.Grade) error
+	SkipWord(id int)
+	SetNewWordsPerDay(n int)
+	GetNextKanji(levels []int) (data.Kanji, error)
+	ReviewKanji(id int, grade srs.Grade) error
+	SkipKanji(id int)
+	SetNewKanjiPerDay(n int)
 	MarkWordAsSeen(id int) error
 	ResetSeenWords(level int) error
+	CompleteLevels(levels []int, policy CompletionPolicy) (LevelCompletion, error)
 	GetWordsCount(levels []int) (int, error)
+	GetLevelProgress() ([]data.LevelProgress, error)
+	GetDecks() ([]data.Deck, error)
+	RecordReview(source string, cardID int64, grade int, duration time.Duration) error
+	GetStats() (Stats, error)
 }
 
 type service struct {
-	repo *db.DB
+	repo           *db.DB
+	newWordsPerDay int
+	newKanjiPerDay int
+	now            func() time.Time
+
+	// skippedWords and skippedKanji hold when each skipped id may be shown
+	// again.
+	skippedWords map[int]time.Time
+	skippedKanji map[int]time.Time
 }
 
 func New(db *db.DB) VocabService {
-	return &service{repo: db}
+	return &service{
+		repo:           db,
+		newWordsPerDay: DefaultNewWordsPerDay,
+		newKanjiPerDay: DefaultNewKanjiPerDay,
+		now:            time.Now,
+		skippedWords:   make(map[int]time.Time),
+		skippedKanji:   make(map[int]time.Time),
+	}
 }
 
-func (s *service) GetNextWord(levels []int) (data.Word, error) {
-	word, err := s.repo.GetNextWord(levels)
-	if err != nil {
+// GetNextWord returns the most overdue word in the given levels and imported
+// decks. When nothing is due, a new word is introduced as long as the daily
+// limit allows it. Skipped words come last.
+func (s *service) GetNextWord(levels []int, decks ...string) (data.Word, error) {
+	now := s.now()
+
+	skipped := activeSkips(s.skippedWords, now)
+	word, err := s.nextWord(levels, now, skipped, decks)
+	if len(skipped) > 0 && (errors.Is(err, ErrorNoWordsDue) || errors.Is(err, ErrorNoWordsFound)) {
+		clear(s.skippedWords)
+		return s.nextWord(levels, now, nil, decks)
+	}
+	return word, err
+}
+
+func (s *service) nextWord(levels []int, now time.Time, exclude []int, decks []string) (data.Word, error) {
+	word, err := s.repo.GetDueWord(levels, now, exclude, decks...)
+	if err == nil {
+		return word, nil
+	}
+	if !errors.Is(err, db.ErrorNoWordsFound) {
 		return data.Word{}, err
 	}
-	err = s.MarkWordAsSeen(word.ID)
+
+	introduced, err := s.repo.CountIntroducedSince(startOfDay(now))
 	if err != nil {
 		return data.Word{}, err
 	}
+	if introduced >= s.newWordsPerDay {
+		return data.Word{}, ErrorNoWordsDue
+	}
 
-	return word, nil
+	return s.repo.GetNextWord(levels, exclude, decks...)
+}
+
+// SkipWord puts the word aside for SkipDelay without grading it.
+func (s *service) SkipWord(id int) {
+	s.skippedWords[id] = s.now().Add(SkipDelay)
+}
+
+// activeSkips returns the ids still put aside, forgetting the others.
+func activeSkips(skipped map[int]time.Time, now time.Time) []int {
+	var ids []int
+	for id, until := range skipped {
+		if now.Before(until) {
+			ids = append(ids, id)
+		} else {
+			delete(skipped, id)
+		}
+	}
+	return ids
+}
+
+// ReviewWord schedules the word according to the grade. The first review of a
+// new word introduces it, which counts it against the daily limit.
+func (s *service) ReviewWord(id int, grade srs.Grade) error {
+	now := s.now()
+
+	state, err := s.repo.GetWordProgress(id, now)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveWordProgress(id, srs.Schedule(state, grade, now), now); err != nil {
+		return err
+	}
+	if err := s.repo.ReintroduceWord(id, now); err != nil {
+		return err
+	}
+	delete(s.skippedWords, id)
+
+	return s.MarkWordAsSeen(id)
+}
+
+func (s *service) SetNewWordsPerDay(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.newWordsPerDay = n
 }
 
 func (s *service) MarkWordAsSeen(id int) error {
@@ -38,11 +153,16 @@ func (s *service) MarkWordAsSeen(id int) error {
 }
 
 func (s *service) CheckIfAllWordsSeen(levels []int) bool {
-	countAllWords, err := s.repo.GetWordsCount(levels)
+	progress, err := s.repo.GetLevelProgress(s.now())
 	if err != nil {
 		return false
 	}
-	return countAllWords == 0
+	for _, p := range progress {
+		if slices.Contains(levels, p.Level) && p.Seen < p.Total {
+			return false
+		}
+	}
+	return true
 }
 
 func (s *service) ResetSeenWords(level int) error {
@@ -52,3 +172,16 @@ func (s *service) ResetSeenWords(level int) error {
 func (s *service) GetWordsCount(levels []int) (int, error) {
 	return s.repo.GetWordsCount(levels)
 }
+
+func (s *service) GetLevelProgress() ([]data.LevelProgress, error) {
+	return s.repo.GetLevelProgress(s.now())
+}
+
+func (s *service) GetDecks() ([]data.Deck, error) {
+	return s.repo.GetDecks()
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
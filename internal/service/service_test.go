@@ -2,9 +2,11 @@ package service
 
 import (
 	"testing"
+	"time"
 
 	"github.com/LealKevin/keiko/internal/data"
 	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/srs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -135,3 +137,331 @@ func TestCheckIfAllWordsSeen(t *testing.T) {
 		assert.True(t, result)
 	})
 }
+
+func TestServiceGetNextWordPrefersDueWords(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+
+	overdue := srs.State{Due: now.Add(-time.Hour), Ease: srs.DefaultEase, Reps: 2, Interval: 6}
+	require.NoError(t, database.SaveWordProgress(2, overdue, now.AddDate(0, 0, -6)))
+
+	word, err := svc.GetNextWord([]int{5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, word.ID)
+}
+
+func TestServiceGetNextWordDailyLimit(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+	svc.SetNewWordsPerDay(1)
+
+	word, err := svc.GetNextWord([]int{5})
+	require.NoError(t, err)
+	require.NoError(t, svc.ReviewWord(word.ID, srs.Good))
+
+	_, err = svc.GetNextWord([]int{5})
+	assert.ErrorIs(t, err, ErrorNoWordsDue)
+
+	svc.(*service).now = func() time.Time { return now.AddDate(0, 0, 1) }
+
+	_, err = svc.GetNextWord([]int{5})
+	assert.NoError(t, err)
+}
+
+func TestServiceReviewWord(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+
+	require.NoError(t, svc.ReviewWord(1, srs.Again))
+
+	state, err := database.GetWordProgress(1, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(srs.RelearnDelay).Unix(), state.Due.Unix())
+
+	var seen int
+	err = database.QueryRow("SELECT seen FROM words WHERE id = 1").Scan(&seen)
+	require.NoError(t, err)
+	assert.Equal(t, 1, seen)
+}
+
+func TestServiceGetNextWordDoesNotScheduleUntilGraded(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	_, err := svc.GetNextWord([]int{5})
+	require.NoError(t, err)
+
+	var count int
+	err = database.QueryRow("SELECT COUNT(*) FROM word_progress").Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestServiceGetStats(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.Local)
+	impl := svc.(*service)
+
+	record := func(day time.Time, grade int) {
+		impl.now = func() time.Time { return day }
+		require.NoError(t, svc.RecordReview(data.SourceVocab, 1, grade, 2*time.Second))
+	}
+
+	record(now, 3)
+	record(now, 1)
+	record(now.AddDate(0, 0, -1), 3)
+	record(now.AddDate(0, 0, -2), 4)
+	record(now.AddDate(0, 0, -4), 3)
+	require.NoError(t, svc.ReviewWord(1, srs.Good))
+
+	impl.now = func() time.Time { return now }
+	stats, err := svc.GetStats()
+	require.NoError(t, err)
+
+	assert.Len(t, stats.Daily, 7)
+	assert.Equal(t, "2025-01-15", stats.Daily[0].Day)
+	assert.Equal(t, 2, stats.Daily[0].Reviews)
+	assert.Equal(t, 0, stats.Daily[3].Reviews)
+	assert.Equal(t, 5, stats.Reviewed)
+	assert.InDelta(t, 0.8, stats.Retention, 0.001)
+	assert.Equal(t, 3, stats.Streak)
+
+	assert.Equal(t, []data.LevelProgress{
+		{Level: 5, Total: 2, Seen: 1, Due: 1},
+		{Level: 4, Total: 1, Seen: 0},
+	}, stats.Levels)
+}
+
+func TestServiceGetStatsStreakFromYesterday(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.Local)
+	impl := svc.(*service)
+
+	impl.now = func() time.Time { return now.AddDate(0, 0, -1) }
+	require.NoError(t, svc.RecordReview(data.SourceAnki, 42, 3, time.Second))
+
+	impl.now = func() time.Time { return now }
+	stats, err := svc.GetStats()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Streak)
+}
+
+func TestCheckIfAllWordsSeenAfterReviews(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	impl := svc.(*service)
+	assert.False(t, impl.CheckIfAllWordsSeen([]int{5}))
+
+	require.NoError(t, svc.MarkWordAsSeen(1))
+	require.NoError(t, svc.MarkWordAsSeen(2))
+
+	assert.True(t, impl.CheckIfAllWordsSeen([]int{5}))
+	assert.False(t, impl.CheckIfAllWordsSeen([]int{4, 5}))
+}
+
+func TestServiceCompleteLevels(t *testing.T) {
+	complete := func(t *testing.T, database *db.DB) {
+		_, err := database.Exec("UPDATE words SET seen = 1 WHERE level = 5")
+		require.NoError(t, err)
+	}
+
+	t.Run("reports nothing while words are unseen", func(t *testing.T) {
+		svc, database := setupTestService(t)
+		defer database.Close()
+
+		result, err := svc.CompleteLevels([]int{5, 4}, PolicyAdvance)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.Completed)
+		assert.Equal(t, []int{5, 4}, result.Levels)
+	})
+
+	t.Run("advance adds the next level", func(t *testing.T) {
+		svc, database := setupTestService(t)
+		defer database.Close()
+		complete(t, database)
+
+		result, err := svc.CompleteLevels([]int{5}, PolicyAdvance)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, result.Completed)
+		assert.Equal(t, []int{5}, result.New)
+		assert.Equal(t, []int{5, 4}, result.Levels)
+	})
+
+	t.Run("reports a level as new once, across restarts", func(t *testing.T) {
+		svc, database := setupTestService(t)
+		defer database.Close()
+		complete(t, database)
+
+		_, err := svc.CompleteLevels([]int{5}, PolicyStop)
+		require.NoError(t, err)
+
+		result, err := New(database).CompleteLevels([]int{5}, PolicyStop)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, result.Completed)
+		assert.Empty(t, result.New)
+
+		// New words make the level incomplete, so it is reported again.
+		require.NoError(t, database.ResetSeenWords(5))
+		_, err = svc.CompleteLevels([]int{5}, PolicyStop)
+		require.NoError(t, err)
+		complete(t, database)
+
+		result, err = svc.CompleteLevels([]int{5}, PolicyStop)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, result.New)
+	})
+
+	t.Run("reset starts the level over", func(t *testing.T) {
+		svc, database := setupTestService(t)
+		defer database.Close()
+		complete(t, database)
+
+		now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+		svc.(*service).now = func() time.Time { return now }
+		svc.SetNewWordsPerDay(1)
+		learned := srs.State{Due: now.AddDate(0, 0, 30), Interval: 30, Ease: srs.DefaultEase, Reps: 5}
+		require.NoError(t, database.SaveWordProgress(1, learned, now.AddDate(0, -2, 0)))
+		require.NoError(t, database.SaveWordProgress(2, learned, now.AddDate(0, -2, 0)))
+
+		result, err := svc.CompleteLevels([]int{5}, PolicyReset)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, result.Completed)
+		assert.Equal(t, []int{5}, result.Levels)
+
+		word, err := svc.GetNextWord([]int{5})
+		require.NoError(t, err)
+		assert.Equal(t, 5, word.Level)
+
+		// The schedule survives the reset, and the word takes a slot of the
+		// daily limit again.
+		state, err := database.GetWordProgress(2, now)
+		require.NoError(t, err)
+		assert.Equal(t, 30, state.Interval)
+
+		require.NoError(t, svc.ReviewWord(word.ID, srs.Good))
+		_, err = svc.GetNextWord([]int{5})
+		assert.ErrorIs(t, err, ErrorNoWordsDue)
+	})
+
+	t.Run("stop keeps the selection", func(t *testing.T) {
+		svc, database := setupTestService(t)
+		defer database.Close()
+		complete(t, database)
+
+		result, err := svc.CompleteLevels([]int{5}, PolicyStop)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, result.Completed)
+		assert.Equal(t, []int{5}, result.Levels)
+
+		_, err = svc.GetNextWord([]int{5})
+		assert.ErrorIs(t, err, ErrorNoWordsFound)
+	})
+}
+
+func TestServiceGetNextKanji(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	_, err := database.SeedKanji([]data.Kanji{
+		{Character: "日", Meanings: []string{"day"}, Strokes: 4, Level: 5},
+		{Character: "月", Meanings: []string{"month"}, Strokes: 4, Level: 5},
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+	svc.SetNewKanjiPerDay(1)
+
+	kanji, err := svc.GetNextKanji([]int{5})
+	require.NoError(t, err)
+	require.NoError(t, svc.ReviewKanji(kanji.ID, srs.Again))
+
+	// The lapsed kanji comes back once its relearn delay is over, even though
+	// the daily limit is reached.
+	_, err = svc.GetNextKanji([]int{5})
+	assert.ErrorIs(t, err, ErrorNoKanjiDue)
+
+	svc.(*service).now = func() time.Time { return now.Add(srs.RelearnDelay) }
+
+	due, err := svc.GetNextKanji([]int{5})
+	require.NoError(t, err)
+	assert.Equal(t, kanji.ID, due.ID)
+
+	svc.SetNewKanjiPerDay(5)
+	_, err = svc.GetNextKanji([]int{1})
+	assert.ErrorIs(t, err, ErrorNoKanjiFound)
+}
+
+func TestServiceSkipWord(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+
+	word, err := svc.GetNextWord([]int{5})
+	require.NoError(t, err)
+
+	svc.SkipWord(word.ID)
+	next, err := svc.GetNextWord([]int{5})
+	require.NoError(t, err)
+	assert.NotEqual(t, word.ID, next.ID)
+
+	// With the other word put aside too, the skips give way.
+	svc.SkipWord(next.ID)
+	again, err := svc.GetNextWord([]int{5})
+	require.NoError(t, err)
+	assert.Contains(t, []int{word.ID, next.ID}, again.ID)
+	assert.Empty(t, svc.(*service).skippedWords)
+
+	// A skip runs out after SkipDelay.
+	svc.SkipWord(word.ID)
+	assert.Equal(t, []int{word.ID}, activeSkips(svc.(*service).skippedWords, now))
+	assert.Empty(t, activeSkips(svc.(*service).skippedWords, now.Add(SkipDelay)))
+}
+
+func TestServiceSkipKanji(t *testing.T) {
+	svc, database := setupTestService(t)
+	defer database.Close()
+
+	_, err := database.SeedKanji([]data.Kanji{
+		{Character: "日", Meanings: []string{"day"}, Strokes: 4, Level: 5},
+		{Character: "月", Meanings: []string{"month"}, Strokes: 4, Level: 5},
+	})
+	require.NoError(t, err)
+
+	kanji, err := svc.GetNextKanji([]int{5})
+	require.NoError(t, err)
+
+	svc.SkipKanji(kanji.ID)
+	next, err := svc.GetNextKanji([]int{5})
+	require.NoError(t, err)
+	assert.NotEqual(t, kanji.ID, next.ID)
+
+	// Grading a kanji lifts its skip.
+	require.NoError(t, svc.ReviewKanji(next.ID, srs.Good))
+	svc.SkipKanji(next.ID)
+	require.NoError(t, svc.ReviewKanji(next.ID, srs.Good))
+	_, skipped := svc.(*service).skippedKanji[next.ID]
+	assert.False(t, skipped)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"time"
+
+	"github.com/LealKevin/keiko/internal/data"
+)
+
+const (
+	statsDays     = 7
+	retentionDays = 30
+)
+
+type Stats struct {
+	// Daily holds the last statsDays days, most recent first, including days
+	// without reviews.
+	Daily     []data.DailyReviews
+	Retention float64 // share of passed reviews over the last retentionDays
+	Reviewed  int     // reviews counted in Retention
+	Streak    int     // consecutive days with reviews, ending today or yesterday
+	Levels    []data.LevelProgress
+}
+
+func (s *service) RecordReview(source string, cardID int64, grade int, duration time.Duration) error {
+	return s.repo.InsertReview(data.Review{
+		Source:     source,
+		CardID:     cardID,
+		Grade:      grade,
+		ReviewedAt: s.now(),
+		Duration:   duration,
+	})
+}
+
+func (s *service) GetStats() (Stats, error) {
+	days, err := s.repo.GetDailyReviews()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	levels, err := s.repo.GetLevelProgress(s.now())
+	if err != nil {
+		return Stats{}, err
+	}
+
+	byDay := make(map[string]data.DailyReviews, len(days))
+	for _, d := range days {
+		byDay[d.Day] = d
+	}
+
+	today := startOfDay(s.now())
+	stats := Stats{Levels: levels}
+	passed := 0
+
+	for i := range retentionDays {
+		day := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		d, ok := byDay[day]
+		if !ok {
+			d = data.DailyReviews{Day: day}
+		}
+		if i < statsDays {
+			stats.Daily = append(stats.Daily, d)
+		}
+		stats.Reviewed += d.Reviews
+		passed += d.Passed
+	}
+	if stats.Reviewed > 0 {
+		stats.Retention = float64(passed) / float64(stats.Reviewed)
+	}
+
+	stats.Streak = streak(byDay, today)
+
+	return stats, nil
+}
+
+// streak counts consecutive review days back from today. A day without
+// reviews yet today does not break a streak that ran until yesterday.
+func streak(byDay map[string]data.DailyReviews, today time.Time) int {
+	day := today
+	if _, ok := byDay[day.Format(time.DateOnly)]; !ok {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	count := 0
+	for {
+		if _, ok := byDay[day.Format(time.DateOnly)]; !ok {
+			return count
+		}
+		count++
+		day = day.AddDate(0, 0, -1)
+	}
+}
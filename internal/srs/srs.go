@@ -0,0 +1,92 @@
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+// Grade mirrors Anki's answer buttons so the same eases can be used for both
+// the built-in vocabulary and AnkiConnect.
+type Grade int
+
+const (
+	Again Grade = iota + 1
+	Hard
+	Good
+	Easy
+)
+
+const (
+	DefaultEase = 2.5
+	MinEase     = 1.3
+
+	// RelearnDelay is how long a failed card waits before it is due again.
+	RelearnDelay = 10 * time.Minute
+
+	// EasyInterval is the first interval of a new card graded Easy, in days,
+	// as Good gives a single day.
+	EasyInterval = 4
+)
+
+type State struct {
+	Due      time.Time
+	Interval int // days
+	Ease     float64
+	Reps     int
+	Lapses   int
+}
+
+func NewState(now time.Time) State {
+	return State{
+		Due:  now,
+		Ease: DefaultEase,
+	}
+}
+
+// Schedule applies an SM-2 style update for the given grade and returns the
+// next state. Intervals follow SM-2 (1 day, 6 days, then interval * ease) with
+// Anki's ease adjustments per button.
+func Schedule(s State, g Grade, now time.Time) State {
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+
+	switch g {
+	case Again:
+		if s.Reps > 0 {
+			s.Lapses++
+		}
+		s.Reps = 0
+		s.Interval = 0
+		s.Ease = math.Max(MinEase, s.Ease-0.20)
+		s.Due = now.Add(RelearnDelay)
+		return s
+	case Hard:
+		s.Ease = math.Max(MinEase, s.Ease-0.15)
+		s.Interval = max(1, int(math.Round(float64(s.Interval)*1.2)))
+	case Easy:
+		if s.Reps == 0 {
+			s.Interval = EasyInterval
+		} else {
+			s.Interval = nextInterval(s) * 13 / 10
+		}
+		s.Ease += 0.15
+	default:
+		s.Interval = nextInterval(s)
+	}
+
+	s.Reps++
+	s.Due = now.AddDate(0, 0, s.Interval)
+	return s
+}
+
+func nextInterval(s State) int {
+	switch s.Reps {
+	case 0:
+		return 1
+	case 1:
+		return 6
+	default:
+		return max(1, int(math.Round(float64(s.Interval)*s.Ease)))
+	}
+}
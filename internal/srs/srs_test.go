@@ -0,0 +1,94 @@
+package srs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func TestNewState(t *testing.T) {
+	s := NewState(now)
+
+	assert.Equal(t, now, s.Due)
+	assert.Equal(t, DefaultEase, s.Ease)
+	assert.Zero(t, s.Interval)
+	assert.Zero(t, s.Reps)
+}
+
+func TestScheduleGoodFollowsSM2Intervals(t *testing.T) {
+	s := NewState(now)
+
+	s = Schedule(s, Good, now)
+	assert.Equal(t, 1, s.Interval)
+	assert.Equal(t, now.AddDate(0, 0, 1), s.Due)
+
+	s = Schedule(s, Good, now)
+	assert.Equal(t, 6, s.Interval)
+
+	s = Schedule(s, Good, now)
+	assert.Equal(t, 15, s.Interval)
+	assert.Equal(t, 3, s.Reps)
+	assert.Equal(t, DefaultEase, s.Ease)
+}
+
+func TestScheduleAgain(t *testing.T) {
+	t.Run("new card does not count as lapse", func(t *testing.T) {
+		s := Schedule(NewState(now), Again, now)
+
+		assert.Zero(t, s.Lapses)
+		assert.Equal(t, now.Add(RelearnDelay), s.Due)
+	})
+
+	t.Run("learned card lapses and resets", func(t *testing.T) {
+		s := Schedule(NewState(now), Good, now)
+		s = Schedule(s, Good, now)
+
+		s = Schedule(s, Again, now)
+
+		assert.Equal(t, 1, s.Lapses)
+		assert.Zero(t, s.Reps)
+		assert.Zero(t, s.Interval)
+		assert.InDelta(t, 2.3, s.Ease, 0.001)
+	})
+
+	t.Run("ease never drops below minimum", func(t *testing.T) {
+		s := NewState(now)
+		for range 20 {
+			s = Schedule(s, Again, now)
+		}
+
+		assert.Equal(t, MinEase, s.Ease)
+	})
+}
+
+func TestScheduleHardAndEasy(t *testing.T) {
+	t.Run("hard grows interval slowly", func(t *testing.T) {
+		s := State{Interval: 10, Ease: DefaultEase, Reps: 3}
+
+		s = Schedule(s, Hard, now)
+
+		assert.Equal(t, 12, s.Interval)
+		assert.InDelta(t, 2.35, s.Ease, 0.001)
+	})
+
+	t.Run("easy adds a bonus", func(t *testing.T) {
+		s := State{Interval: 10, Ease: DefaultEase, Reps: 3}
+
+		s = Schedule(s, Easy, now)
+
+		assert.Equal(t, 32, s.Interval)
+		assert.InDelta(t, 2.65, s.Ease, 0.001)
+	})
+
+	t.Run("easy on a new card beats good", func(t *testing.T) {
+		good := Schedule(NewState(now), Good, now)
+		easy := Schedule(NewState(now), Easy, now)
+
+		assert.Equal(t, 1, good.Interval)
+		assert.Equal(t, EasyInterval, easy.Interval)
+		assert.Equal(t, now.AddDate(0, 0, EasyInterval), easy.Due)
+	})
+}
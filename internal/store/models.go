@@ -58,6 +58,16 @@ func (t *TokensJSON) Scan(value interface{}) error {
 	}
 }
 
+// Sentence is a paragraph quoted as an example of a word, with the article it
+// comes from.
+type Sentence struct {
+	NewsID   int    `json:"news_id"`
+	NHKID    string `json:"nhk_id"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+	RawText  string `json:"raw_text"`
+}
+
 type NewsList struct {
 	ID          int        `json:"id"`
 	NHKID       string     `json:"nhk_id"`
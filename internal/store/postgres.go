@@ -32,6 +32,7 @@ CREATE TABLE IF NOT EXISTS paragraphs (
 CREATE INDEX IF NOT EXISTS idx_news_nhk_id ON news(nhk_id);
 CREATE INDEX IF NOT EXISTS idx_news_created_at ON news(created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_paragraphs_news_id ON paragraphs(news_id);
+CREATE INDEX IF NOT EXISTS idx_paragraphs_tokens ON paragraphs USING GIN (tokens jsonb_path_ops);
 
 CREATE TABLE IF NOT EXISTS scheduler_state (
     key        VARCHAR(50) PRIMARY KEY,
@@ -198,6 +199,39 @@ func (s *Store) GetNewsByID(ctx context.Context, id int) (*NewsWithParagraphs, e
 	return news, rows.Err()
 }
 
+// GetSentences returns paragraphs with a token of the given base form, most
+// recent articles first.
+func (s *Store) GetSentences(ctx context.Context, baseForm string, limit int) ([]Sentence, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text
+		 FROM paragraphs p
+		 JOIN news n ON n.id = p.news_id
+		 WHERE p.tokens @> jsonb_build_array(jsonb_build_object('base_form', $1::text))
+		 ORDER BY n.published_at DESC NULLS LAST, p.position
+		 LIMIT $2`,
+		baseForm, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sentences []Sentence
+	for rows.Next() {
+		var sentence Sentence
+		if err := rows.Scan(&sentence.NewsID, &sentence.NHKID, &sentence.Title, &sentence.Position, &sentence.RawText); err != nil {
+			return nil, err
+		}
+		sentences = append(sentences, sentence)
+	}
+
+	if sentences == nil {
+		sentences = []Sentence{}
+	}
+
+	return sentences, rows.Err()
+}
+
 func (s *Store) GetLastRun(ctx context.Context) (time.Time, error) {
 	var lastRun time.Time
 	err := s.db.QueryRowContext(ctx,
@@ -224,6 +224,48 @@ func TestGetNewsByID(t *testing.T) {
 	})
 }
 
+func TestGetSentences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewWithDB(db)
+	ctx := context.Background()
+
+	t.Run("returns matching paragraphs", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"news_id", "nhk_id", "title", "position", "raw_text"}).
+			AddRow(1, "ne123", "Test News", 0, "雨が降りました。").
+			AddRow(2, "ne456", "Test News 2", 3, "明日も雨です。")
+
+		mock.ExpectQuery("SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text FROM paragraphs p").
+			WithArgs("雨", 5).
+			WillReturnRows(rows)
+
+		sentences, err := s.GetSentences(ctx, "雨", 5)
+
+		assert.NoError(t, err)
+		assert.Len(t, sentences, 2)
+		assert.Equal(t, "ne123", sentences[0].NHKID)
+		assert.Equal(t, "明日も雨です。", sentences[1].RawText)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns empty list", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"news_id", "nhk_id", "title", "position", "raw_text"})
+
+		mock.ExpectQuery("SELECT p.news_id, n.nhk_id, n.title, p.position, p.raw_text FROM paragraphs p").
+			WithArgs("経済", 5).
+			WillReturnRows(rows)
+
+		sentences, err := s.GetSentences(ctx, "経済", 5)
+
+		assert.NoError(t, err)
+		assert.Empty(t, sentences)
+		assert.NotNil(t, sentences)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestGetLastRun(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -11,6 +11,7 @@ type ArticleView struct {
 	detail     *news.NewsDetail
 	tokens     []news.Token
 	paraBreaks []int
+	paraOf     []int // paragraph index of each token
 	lines      []tokenLine
 	cursor     int
 	width      int
@@ -32,17 +33,21 @@ func (a *ArticleView) SetArticle(detail *news.NewsDetail) {
 	a.cursor = 0
 	a.tokens = nil
 	a.paraBreaks = nil
+	a.paraOf = nil
 	a.lines = nil
 
 	if detail == nil {
 		return
 	}
 
-	for _, para := range detail.Paragraphs {
+	for i, para := range detail.Paragraphs {
 		if len(a.tokens) > 0 {
 			a.paraBreaks = append(a.paraBreaks, len(a.tokens))
 		}
 		a.tokens = append(a.tokens, para.Tokens...)
+		for range para.Tokens {
+			a.paraOf = append(a.paraOf, i)
+		}
 	}
 
 	a.computeLines()
@@ -144,6 +149,14 @@ func (a *ArticleView) SelectedToken() *news.Token {
 	return &a.tokens[a.cursor]
 }
 
+// SelectedParagraph returns the paragraph of the selected token.
+func (a *ArticleView) SelectedParagraph() *news.Paragraph {
+	if a.detail == nil || a.cursor < 0 || a.cursor >= len(a.paraOf) {
+		return nil
+	}
+	return &a.detail.Paragraphs[a.paraOf[a.cursor]]
+}
+
 func (a *ArticleView) View() string {
 	if a.detail == nil {
 		return "No article selected"
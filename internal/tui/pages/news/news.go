@@ -1,13 +1,19 @@
 package news
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/data"
 	"github.com/LealKevin/keiko/internal/db"
 	"github.com/LealKevin/keiko/internal/news"
+	"github.com/LealKevin/keiko/internal/service"
 )
 
 type Mode int
@@ -18,6 +24,7 @@ const (
 )
 
 type Model struct {
+	config      *config.Config
 	client      *news.Client
 	db          *db.DB
 	list        list.Model
@@ -29,6 +36,17 @@ type Model struct {
 	currentItem *NewsItem
 	offline     bool
 	loading     bool
+
+	sentences service.SentenceService
+	// example is the line shown under the translation for exampleWord.
+	example     string
+	exampleWord string
+
+	// ankiStatus is the outcome of adding ankiWord to Anki. A duplicate is
+	// only added when the key is pressed again for the same word.
+	ankiStatus       string
+	ankiWord         string
+	confirmDuplicate string
 }
 
 type newsListMsg struct {
@@ -41,7 +59,22 @@ type newsDetailMsg struct {
 	err    error
 }
 
-func New(client *news.Client, db *db.DB) *Model {
+type sentencesMsg struct {
+	word      string
+	sentences []data.Sentence
+	err       error
+}
+
+type ankiNoteMsg struct {
+	word      string
+	deck      string
+	duplicate bool
+	err       error
+}
+
+var errNoAnkiDeck = errors.New("no Anki deck configured, set anki_add_deck or anki_deck")
+
+func New(client *news.Client, db *db.DB, cfg *config.Config) *Model {
 	delegate := NewItemDelegate()
 	l := list.New([]list.Item{}, delegate, 0, 0)
 	l.SetShowTitle(false)
@@ -50,12 +83,14 @@ func New(client *news.Client, db *db.DB) *Model {
 	l.SetShowHelp(false)
 
 	return &Model{
+		config:      cfg,
 		client:      client,
 		db:          db,
 		list:        l,
 		article:     NewArticleView(),
 		translation: NewTranslationPanel(),
 		mode:        ModeList,
+		sentences:   service.NewSentenceService(db, client),
 	}
 }
 
@@ -77,6 +112,56 @@ func (m *Model) fetchNewsDetail(id int) tea.Cmd {
 	}
 }
 
+func (m *Model) fetchSentences(word string) tea.Cmd {
+	return func() tea.Msg {
+		sentences, err := m.sentences.GetSentences(word)
+		return sentencesMsg{word: word, sentences: sentences, err: err}
+	}
+}
+
+// addToAnki adds the token as a note to the configured deck, with its
+// paragraph as the example sentence. Unless force is set, it stops at
+// duplicates so the user can confirm.
+func (m *Model) addToAnki(token news.Token, sentence string, force bool) tea.Cmd {
+	cfg := m.config.UserConfig
+	deck, noteType := cfg.AnkiAddTarget()
+
+	return func() tea.Msg {
+		msg := ankiNoteMsg{word: token.BaseForm, deck: deck}
+		if deck == "" {
+			msg.err = errNoAnkiDeck
+			return msg
+		}
+
+		client := cfg.AnkiClient()
+		note, err := client.NewVocabNote(deck, noteType, anki.VocabNote{
+			Word:     token.BaseForm,
+			Reading:  token.Furigana,
+			Meaning:  token.Translation,
+			Sentence: sentence,
+		})
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+
+		if !force {
+			err := client.CanAddNote(note)
+			if errors.Is(err, anki.ErrorDuplicate) {
+				msg.duplicate = true
+				return msg
+			}
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+		}
+
+		_, msg.err = client.AddNote(note, force)
+		return msg
+	}
+}
+
 func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case newsListMsg:
@@ -109,6 +194,28 @@ func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 		m.article.SetArticle(msg.detail)
 		m.mode = ModeReading
 		return m, nil
+
+	case sentencesMsg:
+		m.exampleWord = msg.word
+		m.example = m.pickExample(msg.sentences, msg.err)
+		return m, nil
+
+	case ankiNoteMsg:
+		m.ankiWord = msg.word
+		switch {
+		case errors.Is(msg.err, anki.ErrorUnauthorized):
+			m.ankiStatus = "Anki rejected the API key, check anki_api_key"
+		case errors.Is(msg.err, anki.ErrorForbidden):
+			m.ankiStatus = "AnkiConnect rejected the request, check webCorsOriginList in its config"
+		case msg.err != nil:
+			m.ankiStatus = fmt.Sprintf("Cannot add to Anki: %s", msg.err)
+		case msg.duplicate:
+			m.confirmDuplicate = msg.word
+			m.ankiStatus = fmt.Sprintf("Already in %s, press a again to add anyway", msg.deck)
+		default:
+			m.ankiStatus = fmt.Sprintf("Added to %s", msg.deck)
+		}
+		return m, nil
 	}
 
 	return m.handleKeyMsg(msg)
@@ -163,12 +270,47 @@ func (m *Model) handleKeyMsg(msg tea.Msg) (*Model, tea.Cmd) {
 			m.article.MoveDown()
 		case "k":
 			m.article.MoveUp()
+		case "e":
+			if token := m.article.SelectedToken(); token != nil && token.BaseForm != "" {
+				m.exampleWord = token.BaseForm
+				m.example = "Looking for an example..."
+				return m, m.fetchSentences(token.BaseForm)
+			}
+		case "a":
+			token := m.article.SelectedToken()
+			if token == nil || token.BaseForm == "" {
+				return m, nil
+			}
+			sentence := ""
+			if para := m.article.SelectedParagraph(); para != nil {
+				sentence = para.RawText
+			}
+			force := m.confirmDuplicate == token.BaseForm
+			m.confirmDuplicate = ""
+			m.ankiWord = token.BaseForm
+			m.ankiStatus = "Adding to Anki..."
+			return m, m.addToAnki(*token, sentence, force)
 		}
 	}
 
 	return m, nil
 }
 
+// pickExample prefers a sentence from another article than the one being
+// read.
+func (m *Model) pickExample(sentences []data.Sentence, err error) string {
+	if err != nil {
+		return "Cannot reach news server for examples"
+	}
+
+	for _, s := range sentences {
+		if m.currentItem == nil || s.NhkID != m.currentItem.NhkID {
+			return "例: " + s.Text
+		}
+	}
+	return "No example in other articles yet"
+}
+
 func (m *Model) SetSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -202,7 +344,7 @@ func (m *Model) View() string {
 	listView := m.list.View()
 	listLines := strings.Split(listStyle.Render(listView), "\n")
 
-	translationHeight := 2
+	translationHeight := 3
 	articleHeight := m.height - translationHeight - 1
 
 	var rightContent string
@@ -221,7 +363,7 @@ func (m *Model) View() string {
 	separator := borderStyle.Render(strings.Repeat("─", contentWidth))
 
 	translationStyle := lipgloss.NewStyle().Width(contentWidth).Height(translationHeight)
-	translationRendered := translationStyle.Render(m.translation.View(m.article.SelectedToken()))
+	translationRendered := translationStyle.Render(m.translation.View(m.article.SelectedToken(), m.exampleFor(m.article.SelectedToken())))
 	translationLines := strings.Split(translationRendered, "\n")
 
 	var rightLines []string
@@ -253,6 +395,18 @@ func (m *Model) View() string {
 	return output.String()
 }
 
+// exampleFor returns the line under the token's translation: the outcome of
+// adding it to Anki, or its example sentence.
+func (m *Model) exampleFor(token *news.Token) string {
+	if token != nil && token.BaseForm == m.ankiWord && m.ankiStatus != "" {
+		return m.ankiStatus
+	}
+	if token == nil || token.BaseForm != m.exampleWord {
+		return ""
+	}
+	return m.example
+}
+
 func (m *Model) Mode() Mode {
 	return m.mode
 }
@@ -18,9 +18,9 @@ func (t *TranslationPanel) SetWidth(width int) {
 	t.width = width
 }
 
-func (t *TranslationPanel) View(token *news.Token) string {
+func (t *TranslationPanel) View(token *news.Token, example string) string {
 	if token == nil {
-		return "Navigate with h/l/j/k to explore tokens"
+		return "Navigate with h/l/j/k to explore tokens, e for an example sentence, a to add to Anki"
 	}
 
 	line1 := fmt.Sprintf("%s【%s】%s", token.BaseForm, token.Furigana, token.Translation)
@@ -34,6 +34,9 @@ func (t *TranslationPanel) View(token *news.Token) string {
 	if line2 != "" {
 		content += "\n" + line2
 	}
+	if example != "" {
+		content += "\n" + example
+	}
 
 	return content
 }
@@ -0,0 +1,225 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fieldMappingState backs the screen assigning roles to the fields of the
+// Anki deck's note types.
+type fieldMappingState struct {
+	noteTypes []anki.NoteType
+	err       error
+
+	noteTypeCursor int
+	fieldCursor    int
+	// editing is the note type whose fields are shown, or -1 for the list.
+	editing int
+}
+
+func (m *Model) openFieldMapping() {
+	if !m.ankiConnected || m.config.UserConfig.AnkiDeck == "" {
+		return
+	}
+
+	noteTypes, err := m.ankiClient.GetDeckNoteTypes(m.config.UserConfig.AnkiDeck)
+	m.fieldMapping = fieldMappingState{noteTypes: noteTypes, err: err, editing: -1}
+	m.currentView = viewFieldMapping
+}
+
+func (m *Model) updateFieldMapping(msg tea.KeyMsg) (*Model, tea.Cmd) {
+	fm := &m.fieldMapping
+
+	if fm.editing < 0 {
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			m.currentView = viewMain
+		case "down", "j":
+			fm.noteTypeCursor = max(min(fm.noteTypeCursor+1, len(fm.noteTypes)-1), 0)
+		case "up", "k":
+			fm.noteTypeCursor = max(fm.noteTypeCursor-1, 0)
+		case "enter":
+			if len(fm.noteTypes) > 0 {
+				fm.editing = fm.noteTypeCursor
+				fm.fieldCursor = 0
+			}
+		}
+		return m, nil
+	}
+
+	noteType := fm.noteTypes[fm.editing]
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		fm.editing = -1
+	case "down", "j":
+		fm.fieldCursor = max(min(fm.fieldCursor+1, len(noteType.Fields)-1), 0)
+	case "up", "k":
+		fm.fieldCursor = max(fm.fieldCursor-1, 0)
+	case "q":
+		m.assignFieldRole(noteType, "question")
+	case "r":
+		m.assignFieldRole(noteType, "reading")
+	case "a":
+		m.assignFieldRole(noteType, "answer")
+	case "s":
+		m.assignFieldRole(noteType, "sentence")
+	case "x", "backspace":
+		m.assignFieldRole(noteType, "")
+	}
+	return m, nil
+}
+
+// assignFieldRole gives the field under the cursor a role, taking it away from
+// the field that had it. An empty role clears the field.
+func (m *Model) assignFieldRole(noteType anki.NoteType, role string) {
+	if len(noteType.Fields) == 0 {
+		return
+	}
+	field := noteType.Fields[m.fieldMapping.fieldCursor]
+
+	mapping, _ := m.config.UserConfig.AnkiNoteType(noteType.Name)
+	for _, r := range []*string{&mapping.Question, &mapping.Reading, &mapping.Answer, &mapping.Sentence} {
+		if *r == field {
+			*r = ""
+		}
+	}
+
+	switch role {
+	case "question":
+		mapping.Question = field
+	case "reading":
+		mapping.Reading = field
+	case "answer":
+		mapping.Answer = field
+	case "sentence":
+		mapping.Sentence = field
+	}
+
+	m.config.SetAnkiNoteType(mapping)
+}
+
+func fieldRole(mapping config.AnkiNoteType, field string) string {
+	switch field {
+	case mapping.Question:
+		return "question"
+	case mapping.Reading:
+		return "reading"
+	case mapping.Answer:
+		return "answer"
+	case mapping.Sentence:
+		return "sentence"
+	}
+	return ""
+}
+
+func (m *Model) renderAnkiFieldsField(focused bool) string {
+	var display string
+	if !m.ankiConnected {
+		display = "(Anki not connected)"
+	} else if m.config.UserConfig.AnkiDeck == "" {
+		display = "(select a deck first)"
+	} else {
+		display = fmt.Sprintf("%d note type(s) mapped. Press Enter to edit", len(m.config.UserConfig.AnkiNoteTypes))
+	}
+
+	if focused && m.focus == fieldAnkiFields {
+		return activeField.Render(display)
+	}
+	return inactiveField.Render(display)
+}
+
+func (m *Model) renderFieldMapping() string {
+	var doc strings.Builder
+	fm := m.fieldMapping
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	unselectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	if fm.err != nil {
+		doc.WriteString(titleStyle.Render("Anki Fields"))
+		doc.WriteString("\n\n")
+		doc.WriteString(inactiveField.Render(fmt.Sprintf("Could not load note types: %v", fm.err)))
+		doc.WriteString("\n\n")
+		doc.WriteString(inactiveField.Render("Press Esc to go back"))
+		return doc.String()
+	}
+
+	if fm.editing < 0 {
+		doc.WriteString(titleStyle.Render(fmt.Sprintf("Note types in %s", m.config.UserConfig.AnkiDeck)))
+		doc.WriteString("\n\n")
+
+		if len(fm.noteTypes) == 0 {
+			doc.WriteString(inactiveField.Render("No notes in this deck."))
+			doc.WriteString("\n\n")
+			doc.WriteString(inactiveField.Render("Press Esc to go back"))
+			return doc.String()
+		}
+
+		for i, nt := range fm.noteTypes {
+			_, mapped := m.config.UserConfig.AnkiNoteType(nt.Name)
+
+			prefix := "  "
+			if i == fm.noteTypeCursor {
+				prefix = "> "
+			}
+			status := "guessed"
+			if mapped {
+				status = "mapped"
+			}
+			line := fmt.Sprintf("%s%s %d fields, %s", prefix, truncateAndPad(nt.Name, 35), len(nt.Fields), status)
+
+			if i == fm.noteTypeCursor {
+				doc.WriteString(cursorStyle.Render(line))
+			} else if mapped {
+				doc.WriteString(selectedStyle.Render(line))
+			} else {
+				doc.WriteString(unselectedStyle.Render(line))
+			}
+			doc.WriteString("\n")
+		}
+
+		doc.WriteString("\n")
+		doc.WriteString(inactiveField.Render("↑↓ navigate  Enter edit fields  Esc back"))
+		return doc.String()
+	}
+
+	noteType := fm.noteTypes[fm.editing]
+	mapping, _ := m.config.UserConfig.AnkiNoteType(noteType.Name)
+
+	doc.WriteString(titleStyle.Render(fmt.Sprintf("Fields of %s", noteType.Name)))
+	doc.WriteString("\n\n")
+
+	for i, field := range noteType.Fields {
+		role := fieldRole(mapping, field)
+
+		prefix := "  "
+		if i == fm.fieldCursor {
+			prefix = "> "
+		}
+		label := ""
+		if role != "" {
+			label = "[" + role + "]"
+		}
+		line := fmt.Sprintf("%s%s %s", prefix, truncateAndPad(field, 35), label)
+
+		if i == fm.fieldCursor {
+			doc.WriteString(cursorStyle.Render(line))
+		} else if role != "" {
+			doc.WriteString(selectedStyle.Render(line))
+		} else {
+			doc.WriteString(unselectedStyle.Render(line))
+		}
+		doc.WriteString("\n")
+	}
+
+	doc.WriteString("\n")
+	doc.WriteString(inactiveField.Render("q question  r reading  a answer  s sentence  x clear  Esc back"))
+	return doc.String()
+}
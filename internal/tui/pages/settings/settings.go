@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/LealKevin/keiko/internal/anki"
 	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/service"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -18,6 +21,7 @@ type settingsView int
 const (
 	viewMain settingsView = iota
 	viewDeckSelector
+	viewFieldMapping
 )
 
 type field int
@@ -25,8 +29,11 @@ type field int
 const (
 	fieldLoopInterval field = iota
 	fieldJLPTLevel
+	fieldDecks
+	fieldStudy
 	fieldVisibility
 	fieldAnkiDeck
+	fieldAnkiFields
 	fieldCount
 )
 
@@ -35,8 +42,13 @@ type Model struct {
 
 	focus            field
 	jlptCursor       int
+	vocabDeckCursor  int
+	studyCursor      int
 	visibilityCursor int
 
+	vocabDecks    []data.Deck
+	levelProgress map[int]data.LevelProgress
+
 	visibilityLabels []string
 
 	loopIntervalInput textinput.Model
@@ -45,8 +57,11 @@ type Model struct {
 	deckCursor        int
 	availableDecks    []anki.DeckInfo
 	ankiConnected     bool
-	ankiClient        *anki.Client
+	ankiRefusal       string
+	ankiClient        anki.Backend
 	quitOnDeckSelect  bool // true when opened via --deck-selector
+
+	fieldMapping fieldMappingState
 }
 
 func createInput(config *config.Config, field field) textinput.Model {
@@ -59,12 +74,24 @@ func createInput(config *config.Config, field field) textinput.Model {
 	return ti
 }
 
-func New(config *config.Config, openDeckSelector bool) *Model {
+// ankiRefusal explains why AnkiConnect refuses keiko, if it does.
+func ankiRefusal(err error) string {
+	switch {
+	case errors.Is(err, anki.ErrorUnauthorized):
+		return "Anki rejected the API key, check anki_api_key"
+	case errors.Is(err, anki.ErrorForbidden):
+		return "AnkiConnect rejected the request, check webCorsOriginList in its config"
+	}
+	return ""
+}
+
+func New(config *config.Config, svc service.VocabService, openDeckSelector bool) *Model {
 	loopIntervalInput := createInput(config, fieldLoopInterval)
-	visibilityLabels := []string{"Furigana", "Translation", "JLPT Level"}
+	visibilityLabels := []string{"Furigana", "Translation", "JLPT Level", "Progress", "Sentence"}
 
-	ankiClient := anki.NewClient()
-	ankiConnected := ankiClient.IsConnected()
+	ankiClient := config.UserConfig.AnkiBackend()
+	ankiErr := ankiClient.Ping()
+	ankiConnected := ankiErr == nil
 
 	var availableDecks []anki.DeckInfo
 	if ankiConnected {
@@ -74,19 +101,36 @@ func New(config *config.Config, openDeckSelector bool) *Model {
 		}
 	}
 
+	vocabDecks, _ := svc.GetDecks()
+
+	levelProgress := make(map[int]data.LevelProgress)
+	if progress, err := svc.GetLevelProgress(); err == nil {
+		for _, p := range progress {
+			levelProgress[p.Level] = p
+		}
+	}
+
 	m := &Model{
 		config: config,
 		focus:  fieldLoopInterval,
 
+		vocabDecks:    vocabDecks,
+		levelProgress: levelProgress,
+
 		loopIntervalInput: loopIntervalInput,
 		visibilityLabels:  visibilityLabels,
 
 		ankiClient:       ankiClient,
 		ankiConnected:    ankiConnected,
+		ankiRefusal:      ankiRefusal(ankiErr),
 		availableDecks:   availableDecks,
 		quitOnDeckSelect: openDeckSelector,
 	}
 
+	if i := slices.Index(studyModes, config.UserConfig.Study); i >= 0 {
+		m.studyCursor = i
+	}
+
 	if openDeckSelector {
 		m.focus = fieldAnkiDeck
 		m.currentView = viewDeckSelector
@@ -113,6 +157,9 @@ func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 		if m.currentView == viewDeckSelector {
 			return m.updateDeckSelector(msg)
 		}
+		if m.currentView == viewFieldMapping {
+			return m.updateFieldMapping(msg)
+		}
 		return m.updateMainView(msg)
 	}
 	var cmd tea.Cmd
@@ -167,6 +214,10 @@ func (m *Model) updateMainView(msg tea.KeyMsg) (*Model, tea.Cmd) {
 				return m, nil
 			}
 			m.jlptCursor = max(m.jlptCursor+1, 0)
+		case fieldDecks:
+			m.vocabDeckCursor = max(min(m.vocabDeckCursor+1, len(m.vocabDecks)-1), 0)
+		case fieldStudy:
+			m.studyCursor = min(m.studyCursor+1, len(studyModes)-1)
 		case fieldVisibility:
 			if m.visibilityCursor == len(m.visibilityLabels)-1 {
 				return m, nil
@@ -181,6 +232,10 @@ func (m *Model) updateMainView(msg tea.KeyMsg) (*Model, tea.Cmd) {
 				return m, nil
 			}
 			m.jlptCursor = min(m.jlptCursor-1, len(JLPTLEVELS)-1)
+		case fieldDecks:
+			m.vocabDeckCursor = max(m.vocabDeckCursor-1, 0)
+		case fieldStudy:
+			m.studyCursor = max(m.studyCursor-1, 0)
 		case fieldVisibility:
 			if m.visibilityCursor == 0 {
 				return m, nil
@@ -202,6 +257,24 @@ func (m *Model) updateMainView(msg tea.KeyMsg) (*Model, tea.Cmd) {
 				m.config.Save()
 				return m, nil
 			}
+		case fieldDecks:
+			if len(m.vocabDecks) == 0 {
+				return m, nil
+			}
+			name := m.vocabDecks[m.vocabDeckCursor].Name
+			if slices.Contains(m.config.UserConfig.Decks, name) {
+				m.config.UserConfig.Decks = slices.DeleteFunc(m.config.UserConfig.Decks, func(d string) bool {
+					return d == name
+				})
+			} else {
+				m.config.UserConfig.Decks = append(m.config.UserConfig.Decks, name)
+			}
+			m.config.Save()
+			return m, nil
+		case fieldStudy:
+			m.config.UserConfig.Study = studyModes[m.studyCursor]
+			m.config.Save()
+			return m, nil
 		case fieldVisibility:
 			if m.visibilityCursor == 0 {
 				m.config.ToggleFurigana()
@@ -209,6 +282,10 @@ func (m *Model) updateMainView(msg tea.KeyMsg) (*Model, tea.Cmd) {
 				m.config.ToggleTranslation()
 			} else if m.visibilityCursor == 2 {
 				m.config.ToggleJLPTLevel()
+			} else if m.visibilityCursor == 3 {
+				m.config.ToggleProgress()
+			} else if m.visibilityCursor == 4 {
+				m.config.ToggleSentence()
 			}
 		case fieldAnkiDeck:
 			m.currentView = viewDeckSelector
@@ -219,6 +296,8 @@ func (m *Model) updateMainView(msg tea.KeyMsg) (*Model, tea.Cmd) {
 					break
 				}
 			}
+		case fieldAnkiFields:
+			m.openFieldMapping()
 		}
 		return m, nil
 	}
@@ -241,12 +320,18 @@ var (
 				Padding(0, 1)
 
 	JLPTLEVELS = []int{5, 4, 3, 2, 1}
+
+	studyModes  = []string{config.StudyVocab, config.StudyKanji, config.StudyBoth}
+	studyLabels = []string{"Vocab", "Kanji", "Both"}
 )
 
 func (m *Model) View(focused bool) string {
 	if m.currentView == viewDeckSelector {
 		return m.renderDeckSelector()
 	}
+	if m.currentView == viewFieldMapping {
+		return m.renderFieldMapping()
+	}
 	return m.renderMainView(focused)
 }
 
@@ -263,6 +348,16 @@ func (m *Model) renderMainView(focused bool) string {
 		m.renderJLPTField(focused),
 	}...)
 
+	decks := lipgloss.JoinHorizontal(lipgloss.Center, []string{
+		m.renderField("Decks: ", focused && m.focus == fieldDecks),
+		m.renderDecksField(focused),
+	}...)
+
+	study := lipgloss.JoinHorizontal(lipgloss.Center, []string{
+		m.renderField("Study: ", focused && m.focus == fieldStudy),
+		m.renderStudyField(focused),
+	}...)
+
 	visibility := lipgloss.JoinHorizontal(lipgloss.Center, []string{
 		m.renderField("Visibility: ", focused && m.focus == fieldVisibility),
 		m.renderVisibilityField(focused),
@@ -277,16 +372,31 @@ func (m *Model) renderMainView(focused bool) string {
 	doc.WriteString("\n")
 	doc.WriteString(jlpt)
 	doc.WriteString("\n")
+	if focused && m.focus == fieldJLPTLevel {
+		doc.WriteString(m.renderLevelDetail())
+		doc.WriteString("\n")
+	}
+	doc.WriteString(decks)
+	doc.WriteString("\n")
+	doc.WriteString(study)
+	doc.WriteString("\n")
 	doc.WriteString(visibility)
 	doc.WriteString("\n")
 	doc.WriteString(ankiDeck)
+	doc.WriteString("\n")
+	doc.WriteString(lipgloss.JoinHorizontal(lipgloss.Center, []string{
+		m.renderField("Anki Fields: ", focused && m.focus == fieldAnkiFields),
+		m.renderAnkiFieldsField(focused),
+	}...))
 
 	return doc.String()
 }
 
 func (m *Model) renderAnkiDeckField(focused bool) string {
 	var display string
-	if !m.ankiConnected {
+	if m.ankiRefusal != "" {
+		display = fmt.Sprintf("(%s)", m.ankiRefusal)
+	} else if !m.ankiConnected {
 		display = "(Anki not connected)"
 	} else if m.config.UserConfig.AnkiDeck == "" {
 		display = "(none selected) Press Enter to choose"
@@ -300,6 +410,14 @@ func (m *Model) renderAnkiDeckField(focused bool) string {
 		}
 		display = fmt.Sprintf("%s (%d due) Press Enter to change", m.config.UserConfig.AnkiDeck, dueCount)
 	}
+	if m.ankiConnected && m.ankiClient.ReadOnly() {
+		display += " [read-only, Anki is closed]"
+	}
+
+	// Extra decks and the search are only set in the config file.
+	if src := m.config.UserConfig.AnkiSource(); len(src.Decks) > 1 || src.Query != "" {
+		display += fmt.Sprintf(" [reviewing %s]", src.Search())
+	}
 
 	if focused && m.focus == fieldAnkiDeck {
 		return activeField.Render(display)
@@ -314,6 +432,13 @@ func (m *Model) renderDeckSelector() string {
 	doc.WriteString(titleStyle.Render("Select Anki Deck"))
 	doc.WriteString("\n\n")
 
+	if m.ankiRefusal != "" {
+		doc.WriteString(inactiveField.Render(m.ankiRefusal + "."))
+		doc.WriteString("\n\n")
+		doc.WriteString(inactiveField.Render("Press Esc to go back"))
+		return doc.String()
+	}
+
 	if !m.ankiConnected {
 		doc.WriteString(inactiveField.Render("Anki not connected. Please open Anki Desktop."))
 		doc.WriteString("\n\n")
@@ -391,6 +516,9 @@ func (m *Model) renderJLPTField(focused bool) string {
 		}
 
 		str := fmt.Sprintf("N%d", level)
+		if p, ok := m.levelProgress[level]; ok {
+			str = fmt.Sprintf("N%d %d/%d", level, p.Seen, p.Total)
+		}
 
 		if focused && m.focus == fieldJLPTLevel && i == m.jlptCursor {
 			str = cursorStyle.Render(str)
@@ -405,6 +533,57 @@ func (m *Model) renderJLPTField(focused bool) string {
 	return lipgloss.JoinHorizontal(lipgloss.Top, levels...)
 }
 
+func (m *Model) renderLevelDetail() string {
+	level := JLPTLEVELS[m.jlptCursor]
+	p := m.levelProgress[level]
+	detail := fmt.Sprintf("N%d: %d/%d seen · %d learned · %d due", level, p.Seen, p.Total, p.Learned, p.Due)
+	return m.renderField(detail, false)
+}
+
+func (m *Model) renderDecksField(focused bool) string {
+	if len(m.vocabDecks) == 0 {
+		return m.renderField("(none) Import with: keiko import --deck <name> file.csv", false)
+	}
+
+	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).Underline(true)
+	var decks []string
+	for i, deck := range m.vocabDecks {
+		isSelected := slices.Contains(m.config.UserConfig.Decks, deck.Name)
+
+		str := fmt.Sprintf("%s (%d)", deck.Name, deck.WordCount)
+
+		if focused && m.focus == fieldDecks && i == m.vocabDeckCursor {
+			str = cursorStyle.Render(str)
+		}
+
+		if isSelected {
+			decks = append(decks, JLPTactiveField.Render(str))
+		} else {
+			decks = append(decks, JLPTinactiveField.Render(str))
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, decks...)
+}
+
+func (m *Model) renderStudyField(focused bool) string {
+	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).Underline(true)
+	var modes []string
+	for i, label := range studyLabels {
+		str := label
+
+		if focused && m.focus == fieldStudy && i == m.studyCursor {
+			str = cursorStyle.Render(str)
+		}
+
+		if m.config.UserConfig.Study == studyModes[i] {
+			modes = append(modes, JLPTactiveField.Render(str))
+		} else {
+			modes = append(modes, JLPTinactiveField.Render(str))
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, modes...)
+}
+
 func (m *Model) renderVisibilityField(focused bool) string {
 	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).Underline(true)
 	var visibility []string
@@ -419,6 +598,12 @@ func (m *Model) renderVisibilityField(focused bool) string {
 		if m.config.UserConfig.IsJLPTLevelVisible && i == 2 {
 			isSelected = true
 		}
+		if m.config.UserConfig.IsProgressVisible && i == 3 {
+			isSelected = true
+		}
+		if m.config.UserConfig.IsSentenceVisible && i == 4 {
+			isSelected = true
+		}
 		str := fmt.Sprintf("%s", label)
 
 		if focused && m.focus == fieldVisibility && i == m.visibilityCursor {
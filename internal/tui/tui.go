@@ -8,6 +8,7 @@ import (
 	"github.com/LealKevin/keiko/internal/config"
 	"github.com/LealKevin/keiko/internal/db"
 	"github.com/LealKevin/keiko/internal/news"
+	"github.com/LealKevin/keiko/internal/service"
 	newspage "github.com/LealKevin/keiko/internal/tui/pages/news"
 	"github.com/LealKevin/keiko/internal/tui/pages/settings"
 	tea "github.com/charmbracelet/bubbletea"
@@ -38,8 +39,8 @@ type model struct {
 }
 
 func New(config *config.Config, database *db.DB, newsClient *news.Client, openDeckSelector bool) *model {
-	settingsModel := settings.New(config, openDeckSelector)
-	newsModel := newspage.New(newsClient, database)
+	settingsModel := settings.New(config, service.New(database), openDeckSelector)
+	newsModel := newspage.New(newsClient, database, config)
 
 	m := &model{
 		Tabs:       []string{"News", "Settings"},
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Desktop bar formats for keiko bar.
+const (
+	BarWaybar  = "waybar"
+	BarI3bar   = "i3bar"
+	BarPolybar = "polybar"
+)
+
+// Mouse buttons in i3bar click events.
+const (
+	ButtonLeft  = 1
+	ButtonRight = 3
+)
+
+// barName identifies keiko's block in i3bar click events.
+const barName = "keiko"
+
+// NewBarSink returns a sink printing frames to w in the protocol of a desktop
+// bar: JSON per line for waybar and i3bar, plain lines for polybar.
+func NewBarSink(format string, w io.Writer) (Sink, error) {
+	switch format {
+	case BarWaybar, BarI3bar, BarPolybar:
+		return &barSink{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown bar format %q, use waybar, i3bar or polybar", format)
+}
+
+type barSink struct {
+	format string
+	w      io.Writer
+	last   string
+}
+
+func (b *barSink) Init() error {
+	if b.format != BarI3bar {
+		return nil
+	}
+	// The i3bar header, then an endless array of status lines.
+	_, err := fmt.Fprint(b.w, `{"version": 1, "click_events": true}`+"\n[\n")
+	return err
+}
+
+func (b *barSink) Render(f Frame) error {
+	line, err := b.line(f)
+	if err != nil || line == b.last {
+		return err
+	}
+	b.last = line
+	_, err = fmt.Fprintln(b.w, line)
+	return err
+}
+
+// Close leaves the bar empty.
+func (b *barSink) Close() error {
+	return b.Render(Frame{})
+}
+
+func (b *barSink) line(f Frame) (string, error) {
+	switch b.format {
+	case BarWaybar:
+		// For a custom module with "return-type": "json". The state is the
+		// CSS class and the alt used by format-icons. waybar reads the text
+		// as Pango markup, where card fields may have & or <.
+		line, err := json.Marshal(map[string]string{
+			"text":    html.EscapeString(f.Text()),
+			"tooltip": html.EscapeString(f.Left.String()),
+			"class":   f.State,
+			"alt":     f.State,
+		})
+		return string(line), err
+	case BarI3bar:
+		block := map[string]string{
+			"name":      barName,
+			"instance":  f.State,
+			"full_text": f.Text(),
+		}
+		if f.Foreground != "" {
+			block["color"] = f.Foreground
+		}
+		if f.Background != "" {
+			block["background"] = f.Background
+		}
+		line, err := json.Marshal([]map[string]string{block})
+		return string(line) + ",", err
+	}
+	return polybarLine(f), nil
+}
+
+// polybarLine colours the frame with polybar format tags.
+func polybarLine(f Frame) string {
+	fg := "-"
+	if f.Foreground != "" {
+		fg = f.Foreground
+	}
+
+	line := f.join(func(p Part) string {
+		var b strings.Builder
+		for _, s := range p {
+			text := s.plainText()
+			if s.Color == "" || text == "" {
+				b.WriteString(text)
+				continue
+			}
+			fmt.Fprintf(&b, "%%{F%s}%s%%{F%s}", s.Color, text, fg)
+		}
+		return b.String()
+	})
+	if f.Foreground != "" {
+		line = fmt.Sprintf("%%{F%s}%s%%{F-}", f.Foreground, line)
+	}
+	if f.Background != "" {
+		line = fmt.Sprintf("%%{B%s}%s%%{B-}", f.Background, line)
+	}
+	return line
+}
+
+// ReadI3Clicks passes the buttons of the i3bar click events on keiko's block
+// to click, until r ends.
+func ReadI3Clicks(r io.Reader, click func(button int)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Events are the elements of an endless JSON array, one per line.
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(strings.TrimPrefix(line, "["), ",")
+		if line == "" {
+			continue
+		}
+
+		var event struct {
+			Name   string `json:"name"`
+			Button int    `json:"button"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return fmt.Errorf("error reading i3bar click: %s", err)
+		}
+		if event.Name == barName {
+			click(event.Button)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var barFrame = Frame{Left: plain("[Core2k: 12 due]"), Center: plain("食べる"), Right: plain("[F4]"), State: "question"}
+
+func TestWaybarSink(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := NewBarSink(BarWaybar, &out)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Init())
+	require.NoError(t, sink.Render(barFrame))
+	assert.JSONEq(t, `{"text": "[Core2k: 12 due]  食べる  [F4]", "tooltip": "[Core2k: 12 due]", "class": "question", "alt": "question"}`, out.String())
+}
+
+func TestWaybarSinkEscapesMarkup(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := NewBarSink(BarWaybar, &out)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Render(Frame{Left: plain("[R&D]"), Center: plain("<b>猫</b>"), State: "question"}))
+	assert.JSONEq(t, `{"text": "[R&amp;D]  &lt;b&gt;猫&lt;/b&gt;", "tooltip": "[R&amp;D]", "class": "question", "alt": "question"}`, out.String())
+}
+
+func TestI3barSink(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := NewBarSink(BarI3bar, &out)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Init())
+	require.NoError(t, sink.Render(barFrame))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{"version": 1, "click_events": true}`, lines[0])
+	assert.Equal(t, "[", lines[1])
+	assert.JSONEq(t, `[{"name": "keiko", "instance": "question", "full_text": "[Core2k: 12 due]  食べる  [F4]"}]`, strings.TrimSuffix(lines[2], ","))
+}
+
+func TestReadI3Clicks(t *testing.T) {
+	input := `[
+{"name": "keiko", "instance": "question", "button": 1, "x": 10, "y": 5}
+,{"name": "clock", "button": 1}
+,{"name": "keiko", "instance": "answer", "button": 3}
+`
+	var buttons []int
+	require.NoError(t, ReadI3Clicks(strings.NewReader(input), func(button int) {
+		buttons = append(buttons, button)
+	}))
+	assert.Equal(t, []int{ButtonLeft, ButtonRight}, buttons)
+}
+
+func TestPolybarLineColoursSpans(t *testing.T) {
+	f := Frame{
+		Center:     Part{{Text: "猫", Color: "#fabd2f"}, {Text: " - cat"}},
+		Foreground: "#ebdbb2",
+		Background: "#282828",
+	}
+	assert.Equal(t, "%{B#282828}%{F#ebdbb2}%{F#fabd2f}猫%{F#ebdbb2} - cat%{F-}%{B-}", polybarLine(f))
+}
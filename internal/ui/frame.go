@@ -0,0 +1,81 @@
+package ui
+
+import "strings"
+
+// Frame is one state of the status bar, split in the parts a status line
+// aligns left, centre and right. Sinks decide how to lay it out.
+type Frame struct {
+	Left   Part
+	Center Part
+	Right  Part
+	// State is the review state, such as "question" or "done", for sinks
+	// that style states differently.
+	State string
+	// Foreground and Background are the line colours from the theme, empty
+	// for the display's own.
+	Foreground string
+	Background string
+}
+
+// Part is an aligned part of a frame.
+type Part []Span
+
+// Span is text in the colour of its theme element, or in the line colour
+// when Color is empty. Format spans come from status line templates and may
+// hold tmux styles.
+type Span struct {
+	Text   string
+	Color  string
+	Format bool
+}
+
+// plainText is the span without tmux styles.
+func (s Span) plainText() string {
+	if s.Format {
+		return stripStyles(s.Text)
+	}
+	return s.Text
+}
+
+// plain is a part in the line colour.
+func plain(text string) Part {
+	return Part{{Text: text}}
+}
+
+func (p Part) String() string {
+	var b strings.Builder
+	for _, s := range p {
+		b.WriteString(s.plainText())
+	}
+	return b.String()
+}
+
+// Text is the frame on a single line, for sinks without alignment.
+func (f Frame) Text() string {
+	return f.join(Part.String)
+}
+
+// join renders the non-empty parts and puts them on a single line.
+func (f Frame) join(render func(Part) string) string {
+	var parts []string
+	for _, p := range []Part{f.Left, f.Center, f.Right} {
+		if p.String() != "" {
+			parts = append(parts, render(p))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Sink displays frames: a tmux status line, a terminal, a file...
+type Sink interface {
+	Init() error
+	Render(f Frame) error
+	// Close clears what the sink showed.
+	Close() error
+}
+
+// Popup is implemented by the sinks of terminal multiplexers, which can run
+// a command in a floating window.
+type Popup interface {
+	Popup(args ...string) error
+}
@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Display sinks, chosen with the display config.
+const (
+	SinkTmux   = "tmux"
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkZellij = "zellij"
+	SinkScreen = "screen"
+)
+
+var ErrorUnknownSink = errors.New("unknown display, use tmux, stdout, file, zellij or screen")
+
+// ErrorNoPopup is returned when the display cannot open the settings.
+var ErrorNoPopup = errors.New("the display has no popup, run keiko --tui in a terminal instead")
+
+// NewSink returns the named sink. path is the file written by the file sink.
+func NewSink(name, path string) (Sink, error) {
+	switch name {
+	case SinkTmux, "":
+		return &tmuxSink{}, nil
+	case SinkStdout:
+		return &writerSink{w: os.Stdout}, nil
+	case SinkFile:
+		return &fileSink{path: path}, nil
+	case SinkZellij:
+		return &zellijSink{}, nil
+	case SinkScreen:
+		return &screenSink{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrorUnknownSink, name)
+}
+
+// tmuxSink shows frames on a second tmux status line.
+type tmuxSink struct{}
+
+func (t *tmuxSink) Init() error {
+	if err := exec.Command("tmux", "set", "-g", "status", "2").Run(); err != nil {
+		return fmt.Errorf("error setting tmux status: %s", err)
+	}
+	if err := exec.Command("tmux", "set", "-g", "status-format[1]", "").Run(); err != nil {
+		return fmt.Errorf("error setting tmux status-format: %s", err)
+	}
+	return nil
+}
+
+func (t *tmuxSink) Render(f Frame) error {
+	if err := exec.Command("tmux", "set", "-g", "status-format[1]", tmuxFormat(f)).Run(); err != nil {
+		return fmt.Errorf("error updating tmux status: %s", err)
+	}
+	return nil
+}
+
+// tmuxFormat lays the frame out as a tmux format with left, centre and right
+// alignment.
+func tmuxFormat(f Frame) string {
+	fg, bg := orDefault(f.Foreground), orDefault(f.Background)
+	part := func(p Part) string {
+		var b strings.Builder
+		for _, s := range p {
+			if s.Format {
+				b.WriteString(s.Text)
+				continue
+			}
+			// # starts a tmux format.
+			text := strings.ReplaceAll(s.Text, "#", "##")
+			if s.Color == "" || text == "" {
+				b.WriteString(text)
+				continue
+			}
+			fmt.Fprintf(&b, "#[fg=%s]%s#[fg=%s]", s.Color, text, fg)
+		}
+		return b.String()
+	}
+
+	return fmt.Sprintf("#[fill=%s,bg=%s,fg=%s]#[align=left] %s #[align=centre]%s#[align=right]%s ",
+		bg, bg, fg, part(f.Left), part(f.Center), part(f.Right))
+}
+
+func (t *tmuxSink) Popup(args ...string) error {
+	popup := append([]string{"display-popup", "-w", "80%", "-h", "80%", "-E"}, args...)
+	if err := exec.Command("tmux", popup...).Run(); err != nil {
+		return fmt.Errorf("error opening tmux popup: %s", err)
+	}
+	return nil
+}
+
+func (t *tmuxSink) Close() error {
+	if err := exec.Command("tmux", "set", "-g", "status", "1").Run(); err != nil {
+		return fmt.Errorf("error setting tmux status: %s", err)
+	}
+	if err := exec.Command("tmux", "set", "-g", "status-format[1]", "").Run(); err != nil {
+		return fmt.Errorf("error setting tmux status-format: %s", err)
+	}
+	if err := exec.Command("tmux", "set", "-g", "status", "on").Run(); err != nil {
+		return fmt.Errorf("error setting tmux status: %s", err)
+	}
+	return nil
+}
+
+// orDefault is the colour, or tmux's default one when unset.
+func orDefault(color string) string {
+	if color == "" {
+		return "default"
+	}
+	return color
+}
+
+// writerSink prints each new frame as a line, for a plain terminal or
+// piping into another program.
+type writerSink struct {
+	w    io.Writer
+	last string
+}
+
+func (s *writerSink) Init() error {
+	return nil
+}
+
+func (s *writerSink) Render(f Frame) error {
+	line := f.Text()
+	if line == s.last {
+		return nil
+	}
+	s.last = line
+	_, err := fmt.Fprintln(s.w, line)
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+// fileSink keeps the current frame in a file, for status bars that read
+// one. The file is replaced at once so readers never see half a line.
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) Init() error {
+	return os.MkdirAll(filepath.Dir(s.path), 0o755)
+}
+
+func (s *fileSink) Render(f Frame) error {
+	return s.write(f.Text() + "\n")
+}
+
+func (s *fileSink) Close() error {
+	return s.write("")
+}
+
+func (s *fileSink) write(content string) error {
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("error writing status file: %s", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error writing status file: %s", err)
+	}
+	return nil
+}
+
+// zellijPipe is the zjstatus pipe frames are sent to, shown by adding
+// {pipe_keiko} to the zjstatus format.
+const zellijPipe = "zjstatus::pipe::pipe_keiko::"
+
+// zellijSink sends frames to the zjstatus plugin, as zellij has no status
+// line of its own to set.
+type zellijSink struct{}
+
+func (z *zellijSink) Init() error {
+	if os.Getenv("ZELLIJ") == "" {
+		return errors.New("error starting zellij display: not inside a zellij session")
+	}
+	return nil
+}
+
+func (z *zellijSink) Render(f Frame) error {
+	return z.send(f.Text())
+}
+
+func (z *zellijSink) Close() error {
+	return z.send("")
+}
+
+func (z *zellijSink) Popup(args ...string) error {
+	popup := append([]string{"run", "--floating", "--close-on-exit", "--"}, args...)
+	if err := exec.Command("zellij", popup...).Run(); err != nil {
+		return fmt.Errorf("error opening zellij popup: %s", err)
+	}
+	return nil
+}
+
+func (z *zellijSink) send(text string) error {
+	if err := exec.Command("zellij", "pipe", zellijPipe+text).Run(); err != nil {
+		return fmt.Errorf("error updating zellij status: %s", err)
+	}
+	return nil
+}
+
+// screenSink shows frames on the hardstatus line of GNU screen.
+type screenSink struct{}
+
+func (s *screenSink) Init() error {
+	if err := exec.Command("screen", "-X", "hardstatus", "alwayslastline").Run(); err != nil {
+		return fmt.Errorf("error setting screen hardstatus: %s", err)
+	}
+	return nil
+}
+
+func (s *screenSink) Render(f Frame) error {
+	// % starts a screen string escape.
+	text := strings.ReplaceAll(f.Text(), "%", "%%")
+	if err := exec.Command("screen", "-X", "hardstatus", "string", text).Run(); err != nil {
+		return fmt.Errorf("error updating screen hardstatus: %s", err)
+	}
+	return nil
+}
+
+func (s *screenSink) Close() error {
+	if err := exec.Command("screen", "-X", "hardstatus", "ignore").Run(); err != nil {
+		return fmt.Errorf("error setting screen hardstatus: %s", err)
+	}
+	return nil
+}
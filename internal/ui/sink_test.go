@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameText(t *testing.T) {
+	assert.Equal(t, "[N5 3/662 · 2 due]  猫  [F4]", Frame{Left: plain("[N5 3/662 · 2 due]"), Center: plain("猫"), Right: plain("[F4]")}.Text())
+	assert.Equal(t, "All caught up!", Frame{Center: plain("All caught up!")}.Text())
+}
+
+func TestWriterSinkSkipsRepeatedFrames(t *testing.T) {
+	var out bytes.Buffer
+	sink := &writerSink{w: &out}
+
+	require.NoError(t, sink.Render(Frame{Center: plain("猫")}))
+	require.NoError(t, sink.Render(Frame{Center: plain("猫")}))
+	require.NoError(t, sink.Render(Frame{Center: plain("犬")}))
+	assert.Equal(t, "猫\n犬\n", out.String())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keiko", "status.txt")
+	sink, err := NewSink(SinkFile, path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Init())
+	require.NoError(t, sink.Render(Frame{Center: plain("猫"), Right: plain("[F4]")}))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "猫  [F4]\n", string(content))
+
+	require.NoError(t, sink.Close())
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestNewSinkUnknown(t *testing.T) {
+	_, err := NewSink("wezterm", "")
+	assert.ErrorIs(t, err, ErrorUnknownSink)
+}
+
+func TestPopupDependsOnSink(t *testing.T) {
+	for name, popup := range map[string]bool{SinkTmux: true, SinkZellij: true, SinkStdout: false, SinkFile: false, SinkScreen: false} {
+		sink, err := NewSink(name, "")
+		require.NoError(t, err)
+		_, ok := sink.(Popup)
+		assert.Equal(t, popup, ok, name)
+	}
+
+	sb := &StatusBar{sink: &writerSink{w: io.Discard}}
+	assert.ErrorIs(t, sb.OpenPopup("keiko", "--tui"), ErrorNoPopup)
+}
+
+func TestTmuxFormatColoursSpans(t *testing.T) {
+	f := Frame{
+		Left:       Part{{Text: "[#1 deck]", Color: "#5e81ac"}},
+		Center:     Part{{Text: "猫", Color: "#88c0d0"}, {Text: "  "}, {Text: "JLPT N5"}},
+		Foreground: "#d8dee9",
+	}
+	assert.Equal(t,
+		"#[fill=default,bg=default,fg=#d8dee9]#[align=left] #[fg=#5e81ac][##1 deck]#[fg=#d8dee9] #[align=centre]#[fg=#88c0d0]猫#[fg=#d8dee9]  JLPT N5#[align=right] ",
+		tmuxFormat(f))
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/LealKevin/keiko/internal/config"
+)
+
+func (m Mode) String() string {
+	switch m {
+	case VocabMode:
+		return "vocab"
+	case AnkiMode:
+		return "anki"
+	case KanjiMode:
+		return "kanji"
+	}
+	return "unknown"
+}
+
+// LineData is what status line templates see. For kanji, Word is the
+// character, Furigana its readings and Meaning its meanings; for Anki cards,
+// the question, reading and answer.
+type LineData struct {
+	Mode     string
+	State    string
+	Word     string
+	Furigana string
+	Meaning  string
+	Romaji   string
+	Level    int // JLPT level, 0 for Anki cards and imported words
+	Deck     string
+	Due      int
+	Sentence string
+	Notice   string
+	// Prefix and Hint are what the built-in layout shows on the left and
+	// right, like "[Core2k: 12 due]" and "[F4]".
+	Prefix string
+	Hint   string
+}
+
+// lineTemplate is a parsed format, or the error it failed with.
+type lineTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// templateFrame renders the configured format of the mode and state, if
+// there is one. A broken format is logged once and the built-in layout is
+// used instead.
+func (s *StatusBar) templateFrame(theme config.Theme, state AnkiState, data LineData) (Frame, bool) {
+	format := s.cfg.UserConfig.Format(s.mode.String(), state.String())
+	if format == "" {
+		return Frame{}, false
+	}
+
+	if s.templates == nil {
+		s.templates = make(map[string]lineTemplate)
+	}
+	t, ok := s.templates[format]
+	if !ok {
+		t.tmpl, t.err = template.New("format").Funcs(templateFuncs).Parse(format)
+		if t.err != nil {
+			log.Printf("status line format for %s %s failed: %v", s.mode, state, t.err)
+		}
+		s.templates[format] = t
+	}
+	if t.err != nil {
+		return Frame{}, false
+	}
+
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, escapeLineData(data)); err != nil {
+		log.Printf("status line format for %s %s failed: %v", s.mode, state, err)
+		return Frame{}, false
+	}
+
+	f := s.newFrame(theme, state, nil, Part{{Text: b.String(), Format: true}}, nil)
+	return f, true
+}
+
+// escapeLineData keeps card text from being read as tmux styles.
+func escapeLineData(d LineData) LineData {
+	for _, field := range []*string{&d.Word, &d.Furigana, &d.Meaning, &d.Romaji, &d.Deck, &d.Sentence, &d.Notice, &d.Prefix, &d.Hint} {
+		*field = strings.ReplaceAll(*field, "#", "##")
+	}
+	return d
+}
+
+// templateFuncs are the helpers of status line templates. Styles use the tmux
+// syntax, and are left out by displays other than tmux.
+var templateFuncs = template.FuncMap{
+	"truncate": func(n int, s string) string {
+		return truncateRunes(s, max(n, 4))
+	},
+	// style inserts a tmux style, such as "align=right" or "fg=#e94560".
+	"style": func(spec string) string {
+		return fmt.Sprintf("#[%s]", spec)
+	},
+	"fg": func(color, s string) string {
+		return fmt.Sprintf("#[fg=%s]%s#[fg=default]", color, s)
+	},
+	"bg": func(color, s string) string {
+		return fmt.Sprintf("#[bg=%s]%s#[bg=default]", color, s)
+	},
+	"bold": func(s string) string {
+		return fmt.Sprintf("#[bold]%s#[nobold]", s)
+	},
+}
+
+var tmuxStyleRegex = regexp.MustCompile(`#\[[^\]]*\]`)
+
+// stripStyles turns templated text with tmux styles into plain text.
+func stripStyles(s string) string {
+	s = strings.ReplaceAll(s, "##", "\x00")
+	s = tmuxStyleRegex.ReplaceAllString(s, "")
+	return strings.ReplaceAll(s, "\x00", "#")
+}
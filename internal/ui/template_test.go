@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTemplateStatusBar(formats map[string]map[string]string) (*StatusBar, *bytes.Buffer) {
+	var out bytes.Buffer
+	cfg := &config.Config{UserConfig: config.UserConfig{
+		IsFuriganaVisible:    true,
+		IsTranslationVisible: true,
+		IsJLPTLevelVisible:   true,
+		Keys:                 config.KeyBindings{Reveal: "F4"},
+		Formats:              formats,
+	}}
+	return &StatusBar{
+		cfg:         cfg,
+		sink:        &writerSink{w: &out},
+		mode:        VocabMode,
+		vocabState:  StateQuestion,
+		currentWord: &data.Word{Word: "猫#1", Furigana: "ねこ", Meaning: "cat", Romaji: "neko", Level: 5},
+	}, &out
+}
+
+func TestTemplateFrame(t *testing.T) {
+	sb, out := newTemplateStatusBar(map[string]map[string]string{
+		"vocab": {"question": `{{ fg "#88c0d0" .Word }} N{{ .Level }}{{ style "align=right" }}{{ .Hint }}`},
+	})
+
+	require.NoError(t, sb.redraw())
+	assert.Equal(t, "猫#1 N5[F4]\n", out.String())
+
+	// tmux gets the styles, with the word escaped.
+	assert.Equal(t, "#[fg=#88c0d0]猫##1#[fg=default] N5#[align=right][F4]", sb.frame.Center[0].Text)
+}
+
+func TestTemplateFrameFallsBackToBuiltInLayout(t *testing.T) {
+	// Only the answer has a format, and a broken one.
+	sb, out := newTemplateStatusBar(map[string]map[string]string{
+		"vocab": {"answer": `{{ .Word `},
+	})
+
+	require.NoError(t, sb.redraw())
+	assert.Equal(t, "猫#1  JLPT N5  [F4]\n", out.String())
+
+	out.Reset()
+	sb.vocabState = StateAnswer
+	require.NoError(t, sb.redraw())
+	assert.Contains(t, out.String(), "猫#1 【ねこ】  cat")
+}
+
+func TestTruncateHelper(t *testing.T) {
+	sb, out := newTemplateStatusBar(map[string]map[string]string{
+		"vocab": {"question": `{{ truncate 5 .Romaji }}|{{ truncate 3 "abcdefgh" }}`},
+	})
+	sb.currentWord.Romaji = "nekoneko"
+
+	require.NoError(t, sb.redraw())
+	assert.Equal(t, "ne...|a...\n", out.String())
+}
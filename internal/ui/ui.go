@@ -1,14 +1,19 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/LealKevin/keiko/internal/anki"
 	"github.com/LealKevin/keiko/internal/config"
 	"github.com/LealKevin/keiko/internal/data"
 	"github.com/LealKevin/keiko/internal/service"
+	"github.com/LealKevin/keiko/internal/srs"
 )
 
 type Mode int
@@ -16,6 +21,7 @@ type Mode int
 const (
 	VocabMode Mode = iota
 	AnkiMode
+	KanjiMode
 )
 
 type AnkiState int
@@ -26,66 +32,170 @@ const (
 	StateDisconnected
 	StateDone
 	StateNoDeck
+	// StateUnauthorized means AnkiConnect is reachable but rejected the API
+	// key.
+	StateUnauthorized
+	// StateForbidden means AnkiConnect is reachable but does not trust the
+	// origin of the requests.
+	StateForbidden
 )
 
+func (a AnkiState) String() string {
+	switch a {
+	case StateQuestion:
+		return "question"
+	case StateAnswer:
+		return "answer"
+	case StateDisconnected:
+		return "disconnected"
+	case StateDone:
+		return "done"
+	case StateNoDeck:
+		return "no-deck"
+	case StateUnauthorized:
+		return "unauthorized"
+	case StateForbidden:
+		return "forbidden"
+	}
+	return "unknown"
+}
+
+// StatusBar is driven by the hotkeys, the refresh loop and background card
+// prefetching, so its exported methods hold mu.
 type StatusBar struct {
-	svc         service.VocabService
-	cfg         *config.Config
-	currentWord *data.Word
+	mu sync.Mutex
+
+	// sink displays the frames, and frame is the one shown last. templates
+	// caches the parsed status line formats.
+	sink      Sink
+	frame     Frame
+	templates map[string]lineTemplate
+
+	svc          service.VocabService
+	sentences    service.SentenceService
+	cfg          *config.Config
+	currentWord  *data.Word
+	currentKanji *data.Kanji
+	// vocabState is shared by words and kanji, only one of them is on
+	// screen at a time.
+	vocabState AnkiState
+
+	// notice is the level completion announcement shown until the next
+	// answer.
+	notice string
+	// completionChecked is set once the levels were checked for completion,
+	// and cleared by the answers and config changes that may complete one.
+	completionChecked bool
+	levelProgress     *data.LevelProgress
+	// sentence is the cached example shown with the current word's answer.
+	sentence *data.Sentence
 
 	mode        Mode
-	ankiClient  *anki.Client
+	ankiClient  anki.Backend
 	ankiState   AnkiState
 	currentCard *anki.CardInfo
 	dueCards    []int64
 	dueCount    int
+
+	// cardQueue holds the next cards, loaded ahead from dueCards so that
+	// answering a card needs a single request. queueGen changes whenever
+	// the due cards are reloaded, so late prefetches are dropped.
+	cardQueue   []*anki.CardInfo
+	queueGen    int
+	prefetching bool
+
+	// While AnkiConnect is unreachable, cards are reviewed from the cache
+	// and the answers replayed once it is back.
+	ankiCache      service.AnkiCacheService
+	ankiOffline    bool
+	offlineCards   []data.AnkiCard
+	pendingAnswers int
+	cachedSource   string
+	cachedAt       time.Time
+
+	// The collection is synced with AnkiWeb every AnkiSyncInterval minutes
+	// and after AnkiSyncAfter answers. syncErr is the last failure, shown
+	// until a sync succeeds.
+	syncedAt         time.Time
+	answersSinceSync int
+	syncing          bool
+	syncErr          error
+
+	// shownAt is when the current question appeared, for review durations.
+	shownAt time.Time
+
+	// paused hides the cards and ignores review commands until resumed.
+	paused bool
 }
 
-type StatusBarUI interface {
-	Init()
-	Update(content string)
-	Refresh(levels []int) error
-	Close()
+// Status is the state of the status bar, as reported by keiko ctl status.
+type Status struct {
+	Mode   string `json:"mode"`
+	State  string `json:"state"`
+	Text   string `json:"text"`
+	Due    int    `json:"due"`
+	Paused bool   `json:"paused"`
 }
 
-func NewStatusBar(svc service.VocabService, cfg *config.Config) *StatusBar {
+// prefetchSize is how many cards are loaded per request ahead of time.
+const prefetchSize = 5
+
+func NewStatusBar(svc service.VocabService, sentences service.SentenceService, ankiCache service.AnkiCacheService, cfg *config.Config, sink Sink) *StatusBar {
 	sb := &StatusBar{
+		sink:       sink,
 		svc:        svc,
+		sentences:  sentences,
+		ankiCache:  ankiCache,
 		cfg:        cfg,
-		ankiClient: anki.NewClient(),
+		ankiClient: cfg.UserConfig.AnkiBackend(),
+		syncedAt:   time.Now(),
+	}
+	svc.SetNewWordsPerDay(cfg.UserConfig.NewWordsPerDay)
+	svc.SetNewKanjiPerDay(cfg.UserConfig.NewKanjiPerDay)
+
+	pending, err := ankiCache.PendingAnswers()
+	if err != nil {
+		log.Printf("pending anki answers failed: %v", err)
 	}
+	sb.pendingAnswers = pending
 
 	if cfg.UserConfig.AnkiModeEnabled {
 		sb.mode = AnkiMode
-		if cfg.UserConfig.AnkiDeck == "" {
+		if sb.source().IsEmpty() {
 			sb.ankiState = StateNoDeck
-		} else if sb.ankiClient.IsConnected() {
-			sb.fetchAnkiCards()
+		} else if err := sb.ankiClient.Ping(); err != nil {
+			sb.setAnkiError(err)
 		} else {
-			sb.ankiState = StateDisconnected
+			sb.applyAnkiCards(sb.beginAnkiLoad()())
 		}
+	} else {
+		sb.mode = sb.localMode()
 	}
 
 	return sb
 }
 
 func (s *StatusBar) Init() {
-	if err := exec.Command("tmux", "set", "-g", "status", "2").Run(); err != nil {
-		log.Printf("tmux status set failed: %v", err)
-	}
-	if err := exec.Command("tmux", "set", "-g", "status-format[1]", "").Run(); err != nil {
-		log.Printf("tmux status-format set failed: %v", err)
+	if err := s.sink.Init(); err != nil {
+		log.Printf("display init failed: %v", err)
 	}
 }
 
-const (
-	fillColor = "#1a1a2e"
-	bgColor   = "#1a1a2e"
-	fgColor   = "#e94560"
-	align     = "centre"
-)
-
+// Redraw shows the current state in the status bar.
 func (s *StatusBar) Redraw() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.redraw()
+}
+
+func (s *StatusBar) redraw() error {
+	if s.paused {
+		theme := s.cfg.UserConfig.Theme.Resolve()
+		f := s.newFrame(theme, StateDone, Part{{Text: "[keiko: paused]", Color: theme.Prefix}}, nil, nil)
+		f.State = "paused"
+		s.render(f)
+		return nil
+	}
 	if s.mode == AnkiMode {
 		return s.redrawAnki()
 	}
@@ -93,103 +203,484 @@ func (s *StatusBar) Redraw() error {
 }
 
 func (s *StatusBar) redrawVocab() error {
-	if s.currentWord == nil {
+	theme := s.cfg.UserConfig.Theme.Resolve()
+	var left, center, right Part
+
+	if s.notice != "" {
+		left = Part{{Text: fmt.Sprintf("[%s]", s.notice), Color: theme.Prefix}}
+	} else if s.cfg.UserConfig.IsProgressVisible && s.levelProgress != nil {
+		p := s.levelProgress
+		left = Part{{Text: fmt.Sprintf("[N%d %d/%d · %d due]", p.Level, p.Seen, p.Total, p.Due), Color: theme.Prefix}}
+	}
+
+	switch s.vocabState {
+	case StateDone:
+		center = plain("All caught up!")
+	case StateQuestion:
+		if s.mode == KanjiMode {
+			if s.currentKanji == nil {
+				return nil
+			}
+			center = Part{
+				{Text: s.currentKanji.Character, Color: theme.Word},
+				{Text: "  "},
+				{Text: s.formatKanjiLevel(), Color: theme.Level},
+			}
+		} else {
+			if s.currentWord == nil {
+				return nil
+			}
+			center = Part{
+				{Text: s.currentWord.Word, Color: theme.Word},
+				{Text: "  "},
+				{Text: s.formatLevel(), Color: theme.Level},
+			}
+		}
+		right = Part{{Text: s.revealHint(), Color: theme.Hint}}
+	case StateAnswer:
+		if s.mode == KanjiMode {
+			if s.currentKanji == nil {
+				return nil
+			}
+			center = s.formatKanjiAnswer(theme)
+			right = Part{{Text: s.gradeHint(), Color: theme.Hint}}
+			break
+		}
+		if s.currentWord == nil {
+			return nil
+		}
+		word := s.currentWord
+
+		translation := ""
+		if s.cfg.UserConfig.IsTranslationVisible {
+			translation = word.Meaning
+		}
+
+		furigana := ""
+		if s.cfg.UserConfig.IsFuriganaVisible {
+			furigana = fmt.Sprintf("【%s】", word.Furigana)
+		}
+
+		center = Part{
+			{Text: word.Word, Color: theme.Word},
+			{Text: " "},
+			{Text: furigana, Color: theme.Furigana},
+			{Text: "  "},
+			{Text: translation, Color: theme.Meaning},
+			{Text: " "},
+			{Text: s.formatLevel(), Color: theme.Level},
+		}
+		if s.sentence != nil {
+			center = append(center, Span{Text: fmt.Sprintf("  「%s」", truncateRunes(s.sentence.Text, 40))})
+		}
+		right = Part{{Text: s.gradeHint(), Color: theme.Hint}}
+	}
+
+	if f, ok := s.templateFrame(theme, s.vocabState, s.vocabLineData(left, right)); ok {
+		s.render(f)
 		return nil
 	}
-	word := s.currentWord
+	s.render(s.newFrame(theme, s.vocabState, left, center, right))
+	return nil
+}
 
-	translation := ""
-	if s.cfg.UserConfig.IsTranslationVisible {
-		translation = word.Meaning
+// vocabLineData is the current word or kanji for status line templates.
+// Readings and meanings follow the visibility settings.
+func (s *StatusBar) vocabLineData(left, right Part) LineData {
+	d := LineData{
+		Mode:   s.mode.String(),
+		State:  s.vocabState.String(),
+		Notice: s.notice,
+		Prefix: left.String(),
+		Hint:   right.String(),
+	}
+	if s.levelProgress != nil {
+		d.Due = s.levelProgress.Due
+	}
+	if s.sentence != nil {
+		d.Sentence = s.sentence.Text
+	}
+
+	if s.mode == KanjiMode {
+		if k := s.currentKanji; k != nil {
+			d.Word = k.Character
+			d.Furigana = strings.Join(append(slices.Clone(k.Onyomi), k.Kunyomi...), "、")
+			d.Meaning = strings.Join(k.Meanings, ", ")
+			d.Level = k.Level
+		}
+	} else if w := s.currentWord; w != nil {
+		d.Word = w.Word
+		d.Furigana = w.Furigana
+		d.Meaning = w.Meaning
+		d.Romaji = w.Romaji
+		d.Level = w.Level
+		d.Deck = w.Deck
+	}
+
+	if !s.cfg.UserConfig.IsFuriganaVisible {
+		d.Furigana = ""
+	}
+	if !s.cfg.UserConfig.IsTranslationVisible {
+		d.Meaning = ""
+	}
+	return d
+}
+
+// newFrame puts the parts on a line in the theme colour of the state.
+func (s *StatusBar) newFrame(theme config.Theme, state AnkiState, left, center, right Part) Frame {
+	var fg string
+	switch state {
+	case StateQuestion:
+		fg = theme.Question
+	case StateAnswer:
+		fg = theme.Answer
+	case StateDone:
+		fg = theme.Done
+	default:
+		fg = theme.Disconnected
 	}
 
-	furigana := ""
+	return Frame{
+		Left:       left,
+		Center:     center,
+		Right:      right,
+		State:      state.String(),
+		Foreground: fg,
+		Background: theme.Background,
+	}
+}
+
+// formatLevel shows the JLPT level, or the deck name for imported words.
+func (s *StatusBar) formatLevel() string {
+	if !s.cfg.UserConfig.IsJLPTLevelVisible || s.currentWord == nil {
+		return ""
+	}
+	if s.currentWord.Deck != "" {
+		return fmt.Sprintf("[%s]", s.currentWord.Deck)
+	}
+	return fmt.Sprintf("JLPT N%d", s.currentWord.Level)
+}
+
+// formatKanjiAnswer shows the readings and meanings of the current kanji,
+// following the furigana and translation visibility settings.
+func (s *StatusBar) formatKanjiAnswer(theme config.Theme) Part {
+	k := s.currentKanji
+
+	readings := ""
 	if s.cfg.UserConfig.IsFuriganaVisible {
-		furigana = fmt.Sprintf("【%s】", word.Furigana)
+		var parts []string
+		if len(k.Onyomi) > 0 {
+			parts = append(parts, strings.Join(k.Onyomi, "、"))
+		}
+		if len(k.Kunyomi) > 0 {
+			parts = append(parts, strings.Join(k.Kunyomi, "、"))
+		}
+		if len(parts) > 0 {
+			readings = fmt.Sprintf("【%s】", strings.Join(parts, " | "))
+		}
 	}
 
-	jlptLevel := ""
-	if s.cfg.UserConfig.IsJLPTLevelVisible {
-		jlptLevel = fmt.Sprintf("JLPT N%d", word.Level)
+	meanings := ""
+	if s.cfg.UserConfig.IsTranslationVisible {
+		meanings = truncateRunes(strings.Join(k.Meanings, ", "), 40)
 	}
 
-	content := fmt.Sprintf("#[fill=%s bg=%s,fg=%s,align=%s] %s %s  %s %s", fillColor, bgColor, fgColor, align, word.Word, furigana, translation, jlptLevel)
-	s.Update(content)
+	return Part{
+		{Text: k.Character, Color: theme.Word},
+		{Text: " "},
+		{Text: readings, Color: theme.Furigana},
+		{Text: "  "},
+		{Text: meanings, Color: theme.Meaning},
+		{Text: " "},
+		{Text: s.formatKanjiLevel(), Color: theme.Level},
+	}
+}
 
-	return nil
+func (s *StatusBar) formatKanjiLevel() string {
+	if !s.cfg.UserConfig.IsJLPTLevelVisible || s.currentKanji == nil {
+		return ""
+	}
+	return fmt.Sprintf("JLPT N%d · %d画", s.currentKanji.Level, s.currentKanji.Strokes)
 }
 
 func (s *StatusBar) redrawAnki() error {
-	var left, center, right string
+	theme := s.cfg.UserConfig.Theme.Resolve()
+	var left, center, right Part
+
+	prefix := func(text string) Part {
+		return Part{{Text: text, Color: theme.Prefix}}
+	}
 
 	switch s.ankiState {
 	case StateNoDeck:
-		left = "[Anki: no deck]"
-		center = "Select deck in settings (F2)"
+		left = prefix(s.ankiStatus("no deck"))
+		center = plain(fmt.Sprintf("Select deck in settings (%s)", s.cfg.UserConfig.Keys.Settings))
 	case StateDisconnected:
-		left = "[Anki: disconnected]"
-		center = "Open Anki Desktop"
+		left = prefix(s.ankiStatus("disconnected"))
+		if s.pendingAnswers > 0 {
+			left = prefix(s.ankiStatus(fmt.Sprintf("disconnected, %d to sync", s.pendingAnswers)))
+		}
+		center = plain("Open Anki Desktop")
+	case StateUnauthorized:
+		left = prefix(s.ankiStatus("unauthorized"))
+		center = plain("Check anki_api_key in config")
+	case StateForbidden:
+		left = prefix(s.ankiStatus("forbidden"))
+		center = plain("Check webCorsOriginList in the AnkiConnect config")
 	case StateDone:
-		left = s.formatPrefix()
-		center = "All caught up!"
+		left = prefix(s.formatPrefix())
+		center = plain("All caught up!")
 	case StateQuestion:
 		if s.currentCard == nil {
-			center = "[Anki: loading...]"
+			center = plain(s.ankiStatus("loading..."))
 		} else {
-			left = s.formatPrefix()
-			center = truncateRunes(s.currentCard.Question, 40)
-			right = "[F4]"
+			left = prefix(s.formatPrefix())
+			center = Part{{Text: truncateRunes(s.currentCard.Question, 40), Color: theme.Word}}
+			right = Part{{Text: s.revealHint(), Color: theme.Hint}}
 		}
 	case StateAnswer:
 		if s.currentCard == nil {
-			center = "[Anki: loading...]"
+			center = plain(s.ankiStatus("loading..."))
 		} else {
-			left = s.formatPrefix()
+			left = prefix(s.formatPrefix())
 			// Show word with reading and meaning
-			wordWithReading := s.currentCard.Question
+			reading := ""
 			if s.currentCard.Reading != "" {
-				wordWithReading = fmt.Sprintf("%s【%s】", s.currentCard.Question, s.currentCard.Reading)
+				reading = fmt.Sprintf("【%s】", s.currentCard.Reading)
 			}
-			center = fmt.Sprintf("%s - %s", truncateRunes(wordWithReading, 30), truncateRunes(s.currentCard.Answer, 25))
-			right = "[F5 ✗ | F6 ✓]"
+			center = Part{
+				{Text: truncateRunes(s.currentCard.Question, 30), Color: theme.Word},
+				{Text: reading, Color: theme.Furigana},
+				{Text: " - "},
+				{Text: truncateRunes(s.currentCard.Answer, 25), Color: theme.Meaning},
+			}
+			right = Part{{Text: s.gradeHint(), Color: theme.Hint}}
 		}
 	}
 
-	// Build tmux format with left/center/right alignment
-	content := fmt.Sprintf("#[fill=%s,bg=%s,fg=%s]#[align=left] %s #[align=centre]%s#[align=right]%s ",
-		fillColor, bgColor, fgColor, left, center, right)
-	s.Update(content)
+	if f, ok := s.templateFrame(theme, s.ankiState, s.ankiLineData(left, right)); ok {
+		s.render(f)
+		return nil
+	}
+	s.render(s.newFrame(theme, s.ankiState, left, center, right))
 	return nil
 }
 
+// ankiLineData is the current card for status line templates.
+func (s *StatusBar) ankiLineData(left, right Part) LineData {
+	d := LineData{
+		Mode:   s.mode.String(),
+		State:  s.ankiState.String(),
+		Deck:   s.source().Name(),
+		Due:    s.dueCount,
+		Prefix: left.String(),
+		Hint:   right.String(),
+	}
+	if c := s.currentCard; c != nil {
+		d.Word = c.Question
+		d.Furigana = c.Reading
+		d.Meaning = c.Answer
+		if c.DeckName != "" {
+			d.Deck = c.DeckName
+		}
+	}
+	return d
+}
+
+// Refresh loads the next word or kanji. When studying both, it falls back to
+// the other kind once the current one has nothing left for now.
 func (s *StatusBar) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+func (s *StatusBar) refresh() error {
+	if err := s.refreshLocal(); err != nil {
+		return err
+	}
+	if s.vocabState == StateDone && s.mode != AnkiMode && s.cfg.UserConfig.Study == config.StudyBoth {
+		s.mode = s.otherLocalMode()
+		return s.refreshLocal()
+	}
+	return nil
+}
+
+func (s *StatusBar) refreshLocal() error {
+	if s.mode == KanjiMode {
+		return s.refreshKanji()
+	}
+
+	if !s.completionChecked {
+		s.checkCompletedLevels()
+		s.completionChecked = true
+	}
+
 	levels := s.cfg.UserConfig.JLPTLevel
-	word, err := s.svc.GetNextWord(levels)
+	word, err := s.svc.GetNextWord(levels, s.cfg.UserConfig.Decks...)
 	if err != nil {
+		if errors.Is(err, service.ErrorNoWordsDue) || errors.Is(err, service.ErrorNoWordsFound) {
+			s.currentWord = nil
+			s.vocabState = StateDone
+			return s.redraw()
+		}
 		return err
 	}
 	s.currentWord = &word
+	s.vocabState = StateQuestion
+	s.shownAt = time.Now()
+	s.refreshLevelProgress()
+
+	// Look the sentence up while the question is shown, so revealing the
+	// answer only reads the cache.
+	s.sentence = nil
+	if s.cfg.UserConfig.IsSentenceVisible {
+		go s.prefetchSentences(word.Word)
+	}
 
-	return s.Redraw()
+	return s.redraw()
 }
 
-func (s *StatusBar) Update(content string) {
-	if err := exec.Command("tmux", "set", "-g", "status-format[1]", content).Run(); err != nil {
-		log.Printf("tmux update failed: %v", err)
+func (s *StatusBar) refreshKanji() error {
+	kanji, err := s.svc.GetNextKanji(s.cfg.UserConfig.JLPTLevel)
+	if err != nil {
+		if errors.Is(err, service.ErrorNoKanjiDue) || errors.Is(err, service.ErrorNoKanjiFound) {
+			s.currentKanji = nil
+			s.vocabState = StateDone
+			return s.redraw()
+		}
+		return err
 	}
+	s.currentKanji = &kanji
+	s.vocabState = StateQuestion
+	s.shownAt = time.Now()
+	s.levelProgress = nil
+
+	return s.redraw()
 }
 
-func (s *StatusBar) Close() {
-	if err := exec.Command("tmux", "set", "-g", "status", "1").Run(); err != nil {
-		log.Printf("tmux close status set failed: %v", err)
+func (s *StatusBar) prefetchSentences(word string) {
+	if _, err := s.sentences.GetSentences(word); err != nil {
+		log.Printf("sentence lookup failed: %v", err)
+	}
+}
+
+// loadSentence picks the cached example for the current word, if any.
+func (s *StatusBar) loadSentence() {
+	s.sentence = nil
+	if !s.cfg.UserConfig.IsSentenceVisible || s.mode != VocabMode || s.currentWord == nil {
+		return
+	}
+
+	sentences, err := s.sentences.CachedSentences(s.currentWord.Word)
+	if err != nil {
+		log.Printf("sentence cache failed: %v", err)
+		return
+	}
+	if len(sentences) > 0 {
+		s.sentence = &sentences[0]
+	}
+}
+
+// localMode is the mode used outside Anki for the configured study setting.
+func (s *StatusBar) localMode() Mode {
+	if s.cfg.UserConfig.Study == config.StudyKanji {
+		return KanjiMode
+	}
+	return VocabMode
+}
+
+func (s *StatusBar) otherLocalMode() Mode {
+	if s.mode == KanjiMode {
+		return VocabMode
+	}
+	return KanjiMode
+}
+
+// refreshLevelProgress loads the progress of the current word's level for the
+// optional progress segment.
+func (s *StatusBar) refreshLevelProgress() {
+	s.levelProgress = nil
+	if !s.cfg.UserConfig.IsProgressVisible || s.mode == KanjiMode || s.currentWord == nil || s.currentWord.Deck != "" {
+		return
+	}
+
+	progress, err := s.svc.GetLevelProgress()
+	if err != nil {
+		log.Printf("level progress failed: %v", err)
+		return
+	}
+	for _, p := range progress {
+		if p.Level == s.currentWord.Level {
+			s.levelProgress = &p
+			return
+		}
+	}
+}
+
+// checkCompletedLevels applies the configured completion policy and
+// announces levels that became complete since the last check.
+func (s *StatusBar) checkCompletedLevels() {
+	policy := service.CompletionPolicy(s.cfg.UserConfig.OnLevelComplete)
+	completion, err := s.svc.CompleteLevels(s.cfg.UserConfig.JLPTLevel, policy)
+	if err != nil {
+		log.Printf("level completion check failed: %v", err)
+		return
+	}
+
+	var announced []string
+	for _, level := range completion.New {
+		announced = append(announced, fmt.Sprintf("N%d", level))
+	}
+	if len(announced) > 0 {
+		s.notice = strings.Join(announced, ", ") + " complete"
+	}
+
+	if !slices.Equal(completion.Levels, s.cfg.UserConfig.JLPTLevel) {
+		s.cfg.UserConfig.JLPTLevel = completion.Levels
+		if err := s.cfg.Save(); err != nil {
+			log.Printf("config save failed: %v", err)
+		}
+	}
+}
+
+func (s *StatusBar) render(f Frame) {
+	s.frame = f
+	if err := s.sink.Render(f); err != nil {
+		log.Printf("display update failed: %v", err)
 	}
-	if err := exec.Command("tmux", "set", "-g", "status-format[1]", "").Run(); err != nil {
-		log.Printf("tmux close format set failed: %v", err)
+}
+
+// Frame returns what the status bar shows.
+func (s *StatusBar) Frame() Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frame
+}
+
+func (s *StatusBar) Close() {
+	if err := s.sink.Close(); err != nil {
+		log.Printf("display close failed: %v", err)
 	}
-	if err := exec.Command("tmux", "set", "-g", "status", "on").Run(); err != nil {
-		log.Printf("tmux close status on failed: %v", err)
+}
+
+// OpenPopup runs the command in a popup of the display, when it has one.
+func (s *StatusBar) OpenPopup(args ...string) error {
+	popup, ok := s.sink.(Popup)
+	if !ok {
+		return ErrorNoPopup
 	}
-	fmt.Println("UI closed")
+	return popup.Popup(args...)
+}
+
+func (s *StatusBar) revealHint() string {
+	return fmt.Sprintf("[%s]", s.cfg.UserConfig.Keys.Reveal)
+}
+
+// gradeHint lists the grading keys in Anki's button order.
+func (s *StatusBar) gradeHint() string {
+	keys := s.cfg.UserConfig.Keys
+	return fmt.Sprintf("[%s ✗ | %s ~ | %s ✓ | %s ★]", keys.Again, keys.Hard, keys.Good, keys.Easy)
 }
 
 func truncateRunes(s string, maxRunes int) string {
@@ -201,142 +692,732 @@ func truncateRunes(s string, maxRunes int) string {
 }
 
 func (s *StatusBar) formatPrefix() string {
-	deckName := s.cfg.UserConfig.AnkiDeck
+	deckName := s.source().Name()
 	maxDeckLen := 15
 	runes := []rune(deckName)
 	if len(runes) > maxDeckLen {
 		deckName = string(runes[:maxDeckLen-2]) + ".."
 	}
-	return fmt.Sprintf("[%s: %d due]", deckName, s.dueCount)
+	if s.ankiOffline {
+		return fmt.Sprintf("[%s: %d offline]", deckName, len(s.offlineCards)+1)
+	}
+
+	status := fmt.Sprintf("%d due", s.dueCount)
+	if s.ankiClient.ReadOnly() {
+		status += ", read-only"
+	}
+	if s.syncErr != nil {
+		status += ", sync failed"
+	}
+	return fmt.Sprintf("[%s: %s]", deckName, status)
 }
 
-func (s *StatusBar) fetchAnkiCards() {
-	cards, err := s.ankiClient.GetDueCards(s.cfg.UserConfig.AnkiDeck)
-	if err != nil {
-		s.ankiState = StateDisconnected
-		return
+// ankiStatus is the status shown when there is no card, noting a failed
+// sync like formatPrefix.
+func (s *StatusBar) ankiStatus(status string) string {
+	if s.syncErr != nil {
+		status += ", sync failed"
 	}
+	return fmt.Sprintf("[Anki: %s]", status)
+}
 
-	s.dueCards = cards
+// source is what Anki cards are reviewed from.
+func (s *StatusBar) source() anki.Source {
+	return s.cfg.UserConfig.AnkiSource()
+}
+
+// ankiLoad is what a reload of the due cards got from Anki.
+type ankiLoad struct {
+	gen    int
+	search string
+	due    []int64
+	// cards are the first due cards, or all of them when cached is set.
+	// rest are the due cards left to load.
+	cards  []*anki.CardInfo
+	rest   []int64
+	cached bool
+	err    error
+}
+
+// beginAnkiLoad starts a reload of the due cards and returns its requests,
+// to run without mu so hotkeys do not wait on Anki: replaying the offline
+// answers, listing the due cards and loading the first of them, or all of
+// them when the offline cache is due. Loads and prefetches begun earlier are
+// dropped.
+func (s *StatusBar) beginAnkiLoad() func() ankiLoad {
+	s.queueGen++
+	gen, client, cache, src := s.queueGen, s.ankiClient, s.ankiCache, s.source()
+	replay := s.pendingAnswers > 0
+	refreshCache := s.cachedSource != src.Search() || time.Since(s.cachedAt) > anki.CacheInterval
+
+	return func() ankiLoad {
+		load := ankiLoad{gen: gen, search: src.Search(), cached: refreshCache}
+		if replay {
+			replayAnkiAnswers(client, cache)
+		}
+
+		load.due, load.err = client.GetDueCards(src)
+		if load.err != nil || len(load.due) == 0 {
+			return load
+		}
+
+		// All due cards are loaded for the cache, so they make the queue.
+		ids := load.due
+		if !refreshCache {
+			ids = load.due[:min(prefetchSize, len(load.due))]
+		}
+		load.rest = slices.Clone(load.due[len(ids):])
+		load.cards, load.err = client.GetCardsInfo(ids)
+		return load
+	}
+}
 
-	dueCount, err := s.ankiClient.GetDueCount(s.cfg.UserConfig.AnkiDeck)
+// applyAnkiCards makes the loaded cards the queue, unless another load began
+// since, the queue was dropped or an answer is on screen. It reports whether
+// the load was applied.
+func (s *StatusBar) applyAnkiCards(load ankiLoad) bool {
+	pending, err := s.ankiCache.PendingAnswers()
 	if err != nil {
-		s.dueCount = len(cards)
+		log.Printf("pending anki answers failed: %v", err)
 	} else {
-		s.dueCount = dueCount
+		s.pendingAnswers = pending
 	}
 
-	if len(cards) == 0 {
+	if load.gen != s.queueGen || (s.currentCard != nil && s.ankiState == StateAnswer) {
+		return false
+	}
+	if load.err != nil {
+		s.setAnkiError(load.err)
+		return true
+	}
+
+	// The collection file still shows the cards answered offline as due.
+	due, cards, rest := load.due, load.cards, load.rest
+	if s.pendingAnswers > 0 {
+		pendingIDs := s.pendingCardIDs()
+		isPending := func(id int64) bool { return slices.Contains(pendingIDs, id) }
+		due = slices.DeleteFunc(slices.Clone(due), isPending)
+		rest = slices.DeleteFunc(rest, isPending)
+		cards = slices.DeleteFunc(cards, func(c *anki.CardInfo) bool { return isPending(c.CardID) })
+	}
+	if load.cached {
+		s.cacheAnkiCards(load.search, cards)
+	}
+
+	s.ankiOffline = false
+	s.dueCards = rest
+	s.cardQueue = cards
+	s.dueCount = len(due)
+
+	if len(due) == 0 {
 		s.ankiState = StateDone
 		s.currentCard = nil
+		return true
+	}
+	s.fetchNextCard()
+	return true
+}
+
+// reloadAnkiCards fetches the due cards in the background, showing the
+// loading state meanwhile.
+func (s *StatusBar) reloadAnkiCards() {
+	s.currentCard = nil
+	s.ankiState = StateQuestion
+	load := s.beginAnkiLoad()
+
+	go func() {
+		result := load()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.applyAnkiCards(result) {
+			s.redraw()
+		}
+	}()
+}
+
+// reconnectAnki goes on from Anki once it answers again, replaying the
+// answers given meanwhile. Answers given while reading the collection file
+// wait for AnkiConnect itself. mu is released during the requests.
+func (s *StatusBar) reconnectAnki() {
+	if s.ankiState == StateAnswer {
 		return
 	}
 
-	s.fetchNextCard()
+	client, offline := s.ankiClient, s.ankiOffline
+	s.mu.Unlock()
+	err := client.Ping()
+	s.mu.Lock()
+
+	if err != nil || (!offline && client.ReadOnly()) {
+		return
+	}
+	if s.mode != AnkiMode || s.ankiState == StateAnswer || client != s.ankiClient {
+		return
+	}
+
+	load := s.beginAnkiLoad()
+	s.mu.Unlock()
+	result := load()
+	s.mu.Lock()
+
+	if s.applyAnkiCards(result) {
+		s.redraw()
+	}
 }
 
+// fetchNextCard shows the next card of the queue. It makes no request: when
+// prefetching did not keep up, the card shows as loading until it lands.
 func (s *StatusBar) fetchNextCard() {
-	if len(s.dueCards) == 0 {
-		if s.dueCount > 0 {
-			s.fetchAnkiCards()
+	if len(s.cardQueue) == 0 {
+		s.currentCard = nil
+		s.prefetchCards()
+		if s.prefetching {
+			s.ankiState = StateQuestion
+		} else {
+			s.ankiState = StateDone
+		}
+		return
+	}
+
+	s.currentCard = s.cardQueue[0]
+	s.cardQueue = s.cardQueue[1:]
+	s.ankiState = StateQuestion
+	s.shownAt = time.Now()
+
+	s.prefetchCards()
+}
+
+// takeBatch removes the next cards to load from the due cards.
+func (s *StatusBar) takeBatch() []int64 {
+	n := min(prefetchSize, len(s.dueCards))
+	ids := slices.Clone(s.dueCards[:n])
+	s.dueCards = s.dueCards[n:]
+	return ids
+}
+
+// prefetchCards loads the next batch in the background once the queue runs
+// low.
+func (s *StatusBar) prefetchCards() {
+	if s.prefetching || len(s.cardQueue) > 1 || len(s.dueCards) == 0 {
+		return
+	}
+
+	s.prefetching = true
+	ids := s.takeBatch()
+	gen := s.queueGen
+	client := s.ankiClient
+
+	go func() {
+		cards, err := client.GetCardsInfo(ids)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.prefetching = false
+		if gen != s.queueGen {
 			return
 		}
-		s.ankiState = StateDone
-		s.currentCard = nil
+		if err != nil {
+			// Put the cards back, they are loaded again when needed.
+			log.Printf("anki prefetch failed: %v", err)
+			s.dueCards = append(ids, s.dueCards...)
+			if s.mode == AnkiMode && s.ankiState == StateQuestion && s.currentCard == nil {
+				s.setAnkiError(err)
+				s.redraw()
+			}
+			return
+		}
+
+		s.cardQueue = append(s.cardQueue, cards...)
+		if s.mode == AnkiMode && s.ankiState == StateQuestion && s.currentCard == nil {
+			s.fetchNextCard()
+			s.redraw()
+		}
+	}()
+}
+
+// cacheAnkiCards keeps the due cards for reviewing while Anki is closed.
+func (s *StatusBar) cacheAnkiCards(source string, cards []*anki.CardInfo) {
+	if err := s.ankiCache.CacheCards(source, cards); err != nil {
+		log.Printf("anki card cache failed: %v", err)
 		return
 	}
+	s.cachedSource = source
+	s.cachedAt = time.Now()
+}
+
+// pendingCardIDs returns the cards answered offline.
+func (s *StatusBar) pendingCardIDs() []int64 {
+	pending, err := s.ankiCache.PendingCardIDs()
+	if err != nil {
+		log.Printf("pending anki answers failed: %v", err)
+	}
+	return pending
+}
+
+// replayAnkiAnswers sends the answers given offline to Anki.
+func replayAnkiAnswers(client anki.Backend, cache service.AnkiCacheService) {
+	result, err := cache.Replay(client)
+	if errors.Is(err, anki.ErrorReadOnly) {
+		// Anki is still closed, the answers wait for AnkiConnect.
+	} else if err != nil {
+		log.Printf("anki replay failed: %v", err)
+	} else if result.Replayed > 0 || result.Skipped > 0 {
+		log.Printf("replayed %d offline anki answers, skipped %d changed cards", result.Replayed, result.Skipped)
+	}
+}
+
+// setAnkiError tells AnkiConnect refusing keiko apart from Anki not running,
+// in which case reviews go on from the cache.
+func (s *StatusBar) setAnkiError(err error) {
+	switch {
+	case errors.Is(err, anki.ErrorUnauthorized):
+		s.ankiState = StateUnauthorized
+	case errors.Is(err, anki.ErrorForbidden):
+		s.ankiState = StateForbidden
+	default:
+		s.goOffline()
+	}
+}
 
-	cardID := s.dueCards[0]
-	s.dueCards = s.dueCards[1:]
+// ankiRefused reports whether AnkiConnect refuses keiko until its config
+// changes.
+func (s *StatusBar) ankiRefused() bool {
+	return s.ankiState == StateUnauthorized || s.ankiState == StateForbidden
+}
 
-	card, err := s.ankiClient.GetCardInfo(cardID)
+func (s *StatusBar) goOffline() {
+	cards, err := s.ankiCache.CachedCards(s.source().Search())
 	if err != nil {
+		log.Printf("anki card cache failed: %v", err)
+	}
+
+	s.ankiOffline = true
+	s.queueGen++
+	s.cardQueue = nil
+	s.dueCards = nil
+	s.offlineCards = cards
+	s.nextOfflineCard()
+}
+
+func (s *StatusBar) nextOfflineCard() {
+	if len(s.offlineCards) == 0 {
 		s.ankiState = StateDisconnected
+		s.currentCard = nil
 		return
 	}
 
-	s.currentCard = card
+	card := s.offlineCards[0]
+	s.offlineCards = s.offlineCards[1:]
+
+	s.currentCard = &anki.CardInfo{
+		CardID:   card.CardID,
+		DeckName: card.Deck,
+		Question: card.Question,
+		Reading:  card.Reading,
+		Answer:   card.Answer,
+		Mod:      card.Mod,
+	}
 	s.ankiState = StateQuestion
+	s.shownAt = time.Now()
+}
+
+// offlineCard is the current card as kept in the offline cache.
+func (s *StatusBar) offlineCard() data.AnkiCard {
+	return data.AnkiCard{
+		CardID:   s.currentCard.CardID,
+		Source:   s.source().Search(),
+		Deck:     s.currentCard.DeckName,
+		Question: s.currentCard.Question,
+		Reading:  s.currentCard.Reading,
+		Answer:   s.currentCard.Answer,
+		Mod:      s.currentCard.Mod,
+	}
+}
+
+// answerOffline keeps the answer to the current card until Anki is back.
+func (s *StatusBar) answerOffline(ease int) {
+	card := s.offlineCard()
+	if err := s.ankiCache.AnswerOffline(card, ease); err != nil {
+		log.Printf("offline anki answer failed: %v", err)
+		return
+	}
+	s.pendingAnswers++
+	s.recordReview(data.SourceAnki, card.CardID, ease)
 }
 
 func (s *StatusBar) Mode() Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.mode
 }
 
 func (s *StatusBar) AnkiState() AnkiState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.ankiState
 }
 
+// VocabState reports the question/answer state of the built-in vocabulary
+// and kanji, which share their states with Anki mode.
+func (s *StatusBar) VocabState() AnkiState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vocabState
+}
+
 func (s *StatusBar) ToggleMode() {
-	if s.cfg.UserConfig.AnkiDeck == "" {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused || s.source().IsEmpty() {
 		return
 	}
 
-	if s.mode == VocabMode {
+	if s.mode != AnkiMode {
 		s.mode = AnkiMode
-		if s.ankiClient.IsConnected() {
-			s.fetchAnkiCards()
-		} else {
-			s.ankiState = StateDisconnected
-		}
+		s.reloadAnkiCards()
 	} else {
-		s.mode = VocabMode
+		s.mode = s.localMode()
+		if !s.hasLocalCard() {
+			s.refresh()
+		}
 	}
 
 	s.cfg.UserConfig.AnkiModeEnabled = (s.mode == AnkiMode)
 	s.cfg.Save()
-	s.Redraw()
+	s.redraw()
+}
+
+func (s *StatusBar) hasLocalCard() bool {
+	if s.mode == KanjiMode {
+		return s.currentKanji != nil
+	}
+	return s.currentWord != nil
 }
 
 func (s *StatusBar) NeedsDeckSelector() bool {
-	return s.cfg.UserConfig.AnkiDeck == ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source().IsEmpty()
+}
+
+// Click handles a click on a desktop bar: the left button reveals the answer
+// or grades it good, the right one grades it again.
+func (s *StatusBar) Click(button int) {
+	s.mu.Lock()
+	state := s.vocabState
+	if s.mode == AnkiMode {
+		state = s.ankiState
+	}
+	s.mu.Unlock()
+
+	switch button {
+	case ButtonLeft:
+		if state == StateAnswer {
+			s.AnswerCard(int(srs.Good))
+		} else {
+			s.RevealAnswer()
+		}
+	case ButtonRight:
+		s.AnswerCard(int(srs.Again))
+	}
 }
 
 func (s *StatusBar) RevealAnswer() {
-	if s.mode != AnkiMode || s.ankiState != StateQuestion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
 		return
 	}
-	s.ankiState = StateAnswer
-	s.Redraw()
+
+	switch s.mode {
+	case AnkiMode:
+		if s.ankiState != StateQuestion || s.currentCard == nil {
+			return
+		}
+		s.ankiState = StateAnswer
+	case VocabMode, KanjiMode:
+		if s.vocabState != StateQuestion || !s.hasLocalCard() {
+			return
+		}
+		s.vocabState = StateAnswer
+		s.loadSentence()
+	}
+	s.redraw()
 }
 
+// AnswerCard grades the current card. In Anki mode this is the only request,
+// the next card comes from the prefetched queue.
 func (s *StatusBar) AnswerCard(ease int) {
-	if s.mode != AnkiMode || s.ankiState != StateAnswer || s.currentCard == nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+	if s.mode != AnkiMode {
+		s.answerLocal(ease)
+		return
+	}
+
+	if s.ankiState != StateAnswer || s.currentCard == nil {
+		return
+	}
+
+	if s.ankiOffline {
+		s.answerOffline(ease)
+		s.nextOfflineCard()
+		s.redraw()
 		return
 	}
 
 	err := s.ankiClient.AnswerCard(s.currentCard.CardID, ease)
+	if errors.Is(err, anki.ErrorReadOnly) {
+		// Read from the collection file: keep the answer for AnkiConnect
+		// and go on with the queue.
+		s.answerOffline(ease)
+		s.dueCount = max(s.dueCount-1, 0)
+		s.nextAnkiCard()
+		s.redraw()
+		return
+	}
 	if err != nil {
-		s.ankiState = StateDisconnected
-		s.Redraw()
+		// Anki went away while the card was shown: keep the answer.
+		if !errors.Is(err, anki.ErrorUnauthorized) && !errors.Is(err, anki.ErrorForbidden) {
+			s.answerOffline(ease)
+		}
+		s.setAnkiError(err)
+		s.redraw()
 		return
 	}
+	s.recordReview(data.SourceAnki, s.currentCard.CardID, ease)
+	if err := s.ankiCache.CardAnswered(s.currentCard.CardID); err != nil {
+		log.Printf("anki card cache failed: %v", err)
+	}
+	s.dueCount = max(s.dueCount-1, 0)
+
+	s.answersSinceSync++
+	if after := s.cfg.UserConfig.AnkiSyncAfter; after > 0 && s.answersSinceSync >= after {
+		s.syncAnki()
+	}
 
-	s.fetchAnkiCards()
-	s.Redraw()
+	s.nextAnkiCard()
+	s.redraw()
+}
+
+// nextAnkiCard moves on after an answer. Once the queue is done, it reloads:
+// failed cards and learning steps may be due again.
+func (s *StatusBar) nextAnkiCard() {
+	if len(s.cardQueue) == 0 && len(s.dueCards) == 0 && !s.prefetching {
+		s.reloadAnkiCards()
+	} else {
+		s.fetchNextCard()
+	}
+}
+
+func (s *StatusBar) answerLocal(ease int) {
+	if s.vocabState != StateAnswer || !s.hasLocalCard() {
+		return
+	}
+
+	if s.mode == KanjiMode {
+		if err := s.svc.ReviewKanji(s.currentKanji.ID, srs.Grade(ease)); err != nil {
+			log.Printf("kanji review failed: %v", err)
+			return
+		}
+		s.recordReview(data.SourceKanji, int64(s.currentKanji.ID), ease)
+	} else {
+		if err := s.svc.ReviewWord(s.currentWord.ID, srs.Grade(ease)); err != nil {
+			log.Printf("vocab review failed: %v", err)
+			return
+		}
+		s.recordReview(data.SourceVocab, int64(s.currentWord.ID), ease)
+		s.completionChecked = false
+	}
+	s.notice = ""
+
+	// Alternate between words and kanji when studying both.
+	if s.cfg.UserConfig.Study == config.StudyBoth {
+		s.mode = s.otherLocalMode()
+	}
+	s.refresh()
+}
+
+func (s *StatusBar) recordReview(source string, cardID int64, ease int) {
+	if err := s.svc.RecordReview(source, cardID, ease, time.Since(s.shownAt)); err != nil {
+		log.Printf("review log failed: %v", err)
+	}
 }
 
 func (s *StatusBar) RefreshAnkiDueCount() {
-	if s.mode != AnkiMode || s.ankiState == StateDisconnected {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mode != AnkiMode || s.ankiRefused() {
 		return
 	}
 
-	count, err := s.ankiClient.GetDueCount(s.cfg.UserConfig.AnkiDeck)
+	// Once Anki is back, replay the offline answers and go on from Anki,
+	// but not while an offline answer is on screen.
+	if s.ankiOffline || (s.pendingAnswers > 0 && s.ankiClient.ReadOnly()) {
+		s.reconnectAnki()
+		return
+	}
+
+	// Hotkeys do not wait on the count.
+	src, client := s.source(), s.ankiClient
+	s.mu.Unlock()
+	count, err := client.GetDueCount(src)
+	s.mu.Lock()
+
+	if s.mode != AnkiMode || s.ankiOffline {
+		return
+	}
 	if err != nil {
-		s.ankiState = StateDisconnected
-		s.Redraw()
+		s.setAnkiError(err)
+		s.redraw()
 		return
 	}
 	s.dueCount = count
 }
 
+// Skip moves on without grading: the current Anki card goes to the back of
+// the queue, and the current built-in word or kanji is put aside for a while.
+func (s *StatusBar) Skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+	if s.mode != AnkiMode {
+		if s.mode == KanjiMode && s.currentKanji != nil {
+			s.svc.SkipKanji(s.currentKanji.ID)
+		} else if s.mode == VocabMode && s.currentWord != nil {
+			s.svc.SkipWord(s.currentWord.ID)
+		}
+		s.refresh()
+		return
+	}
+
+	if s.ankiOffline {
+		if s.currentCard != nil && len(s.offlineCards) > 0 {
+			s.offlineCards = append(s.offlineCards, s.offlineCard())
+			s.nextOfflineCard()
+		}
+	} else if s.currentCard != nil && len(s.cardQueue) > 0 {
+		s.cardQueue = append(s.cardQueue, s.currentCard)
+		s.fetchNextCard()
+	} else if s.currentCard != nil && len(s.dueCards) > 0 {
+		s.dueCards = append(s.dueCards, s.currentCard.CardID)
+		s.fetchNextCard()
+	}
+	s.redraw()
+}
+
+// TogglePause pauses or resumes reviews and reports whether they are paused.
+func (s *StatusBar) TogglePause() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = !s.paused
+	s.redraw()
+	return s.paused
+}
+
+func (s *StatusBar) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Mode:   s.mode.String(),
+		State:  s.vocabState.String(),
+		Text:   s.frame.Text(),
+		Paused: s.paused,
+	}
+	if s.mode == AnkiMode {
+		status.State = s.ankiState.String()
+		status.Due = s.dueCount
+	} else if s.levelProgress != nil {
+		status.Due = s.levelProgress.Due
+	}
+	return status
+}
+
+// SyncAnkiIfDue syncs the Anki collection once AnkiSyncInterval has passed
+// since the last sync.
+func (s *StatusBar) SyncAnkiIfDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	interval := time.Duration(s.cfg.UserConfig.AnkiSyncInterval) * time.Minute
+	if interval <= 0 || s.mode != AnkiMode || s.ankiRefused() {
+		return
+	}
+	if time.Since(s.syncedAt) < interval {
+		return
+	}
+	s.syncAnki()
+}
+
+// syncAnki starts a sync in the background, as it can take a while. It is
+// skipped while Anki is unreachable.
+func (s *StatusBar) syncAnki() {
+	if s.syncing || s.ankiOffline {
+		return
+	}
+
+	s.syncing = true
+	s.answersSinceSync = 0
+	client := s.ankiClient
+
+	go func() {
+		err := client.Sync()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.syncing = false
+		s.syncedAt = time.Now()
+		// Anki closed since: there is nothing to sync.
+		if errors.Is(err, anki.ErrorReadOnly) {
+			err = nil
+		}
+		if err != nil {
+			log.Printf("anki sync failed: %v", err)
+		}
+		s.syncErr = err
+		if s.mode == AnkiMode {
+			s.redraw()
+		}
+	}()
+}
+
 func (s *StatusBar) OnConfigChange() {
-	if s.mode == AnkiMode && s.cfg.UserConfig.AnkiDeck != "" {
-		s.fetchAnkiCards()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.svc.SetNewWordsPerDay(s.cfg.UserConfig.NewWordsPerDay)
+	s.svc.SetNewKanjiPerDay(s.cfg.UserConfig.NewKanjiPerDay)
+	s.refreshLevelProgress()
+	s.completionChecked = false
+	s.ankiClient = s.cfg.UserConfig.AnkiBackend()
+
+	// Switch to the newly selected study mode, unless studying both.
+	if s.mode != AnkiMode && s.cfg.UserConfig.Study != config.StudyBoth && s.mode != s.localMode() {
+		s.mode = s.localMode()
+		s.refresh()
+		return
+	}
+	if s.mode == AnkiMode && !s.source().IsEmpty() {
+		s.reloadAnkiCards()
 	}
-	s.Redraw()
+	s.redraw()
 }
 
-func (s *StatusBar) AnkiClient() *anki.Client {
+func (s *StatusBar) AnkiClient() anki.Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.ankiClient
 }
@@ -0,0 +1,420 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LealKevin/keiko/internal/anki"
+	"github.com/LealKevin/keiko/internal/config"
+	"github.com/LealKevin/keiko/internal/data"
+	"github.com/LealKevin/keiko/internal/db"
+	"github.com/LealKevin/keiko/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *db.DB {
+	database, err := db.Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.Migrate())
+	return database
+}
+
+func newLocalStatusBar(t *testing.T, mode Mode) *StatusBar {
+	database := openTestDB(t)
+
+	require.NoError(t, database.SeedVocab([]data.Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Romaji: "inu", Level: 5},
+		{Word: "猫", Meaning: "cat", Furigana: "ねこ", Romaji: "neko", Level: 5},
+	}))
+	_, err := database.SeedKanji([]data.Kanji{
+		{Character: "日", Meanings: []string{"day"}, Strokes: 4, Level: 5},
+		{Character: "月", Meanings: []string{"month"}, Strokes: 4, Level: 5},
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{UserConfig: config.UserConfig{JLPTLevel: []int{5}}}
+	return &StatusBar{
+		cfg:  cfg,
+		svc:  service.New(database),
+		sink: &writerSink{w: &bytes.Buffer{}},
+		mode: mode,
+	}
+}
+
+func TestSkipShowsAnotherWord(t *testing.T) {
+	sb := newLocalStatusBar(t, VocabMode)
+	require.NoError(t, sb.refresh())
+	require.NotNil(t, sb.currentWord)
+	first := sb.currentWord.ID
+
+	sb.Skip()
+
+	require.NotNil(t, sb.currentWord)
+	assert.NotEqual(t, first, sb.currentWord.ID)
+	assert.Equal(t, StateQuestion, sb.vocabState)
+}
+
+func TestSkipShowsAnotherKanji(t *testing.T) {
+	sb := newLocalStatusBar(t, KanjiMode)
+	require.NoError(t, sb.refresh())
+	require.NotNil(t, sb.currentKanji)
+	first := sb.currentKanji.ID
+
+	sb.Skip()
+
+	require.NotNil(t, sb.currentKanji)
+	assert.NotEqual(t, first, sb.currentKanji.ID)
+}
+
+// fakeBackend serves the due cards in due. While gate is open, card lookups
+// wait for it to be closed.
+type fakeBackend struct {
+	mu       sync.Mutex
+	due      []int64
+	answered []int64
+	gate     chan struct{}
+	syncs    int
+	syncErr  error
+}
+
+func (f *fakeBackend) Ping() error    { return nil }
+func (f *fakeBackend) ReadOnly() bool { return false }
+
+func (f *fakeBackend) GetDecksWithStats() ([]anki.DeckInfo, error) { return nil, nil }
+
+func (f *fakeBackend) GetDeckNoteTypes(deck string) ([]anki.NoteType, error) { return nil, nil }
+
+func (f *fakeBackend) GetDueCards(src anki.Source) ([]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.due...), nil
+}
+
+func (f *fakeBackend) GetDueCount(src anki.Source) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.due), nil
+}
+
+func (f *fakeBackend) GetCardsInfo(cardIDs []int64) ([]*anki.CardInfo, error) {
+	f.mu.Lock()
+	gate := f.gate
+	f.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	cards := make([]*anki.CardInfo, len(cardIDs))
+	for i, id := range cardIDs {
+		cards[i] = &anki.CardInfo{CardID: id, DeckName: "Japanese", Question: "q", Answer: "a"}
+	}
+	return cards, nil
+}
+
+func (f *fakeBackend) AnswerCard(cardID int64, ease int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.answered = append(f.answered, cardID)
+	return nil
+}
+
+func (f *fakeBackend) AnswerCards(answers []anki.Answer) ([]bool, error) {
+	return make([]bool, len(answers)), nil
+}
+
+func (f *fakeBackend) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.syncs++
+	return f.syncErr
+}
+
+// block makes card lookups wait until the returned func is called.
+func (f *fakeBackend) block() func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	gate := make(chan struct{})
+	f.gate = gate
+	return func() {
+		f.mu.Lock()
+		f.gate = nil
+		f.mu.Unlock()
+		close(gate)
+	}
+}
+
+func newAnkiStatusBar(t *testing.T, backend *fakeBackend) *StatusBar {
+	database := openTestDB(t)
+	cfg := &config.Config{UserConfig: config.UserConfig{AnkiDeck: "Japanese", AnkiModeEnabled: true}}
+	sb := &StatusBar{
+		cfg:        cfg,
+		svc:        service.New(database),
+		ankiCache:  service.NewAnkiCacheService(database),
+		ankiClient: backend,
+		sink:       &writerSink{w: &bytes.Buffer{}},
+		mode:       AnkiMode,
+	}
+	// The offline cache is fresh, so cards load in batches.
+	sb.cachedSource = sb.source().Search()
+	sb.cachedAt = time.Now()
+	return sb
+}
+
+// currentCardID reads the card on screen, 0 while loading.
+func (s *StatusBar) currentCardID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.currentCard == nil {
+		return 0
+	}
+	return s.currentCard.CardID
+}
+
+func TestAnkiReloadDoesNotHoldLock(t *testing.T) {
+	backend := &fakeBackend{due: []int64{1, 2}}
+	sb := newAnkiStatusBar(t, backend)
+	release := backend.block()
+
+	sb.mu.Lock()
+	sb.reloadAnkiCards()
+	sb.mu.Unlock()
+
+	// Hotkeys go through while the cards load.
+	done := make(chan struct{})
+	go func() {
+		sb.RevealAnswer()
+		sb.Status()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hotkeys waited on the reload")
+	}
+	assert.Equal(t, StateQuestion, sb.AnkiState())
+	assert.Zero(t, sb.currentCardID())
+
+	release()
+	assert.Eventually(t, func() bool { return sb.currentCardID() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, sb.Status().Due)
+}
+
+func TestAnkiAnswersDoNotWaitOnPrefetch(t *testing.T) {
+	backend := &fakeBackend{due: []int64{1, 2, 3, 4, 5, 6, 7}}
+	sb := newAnkiStatusBar(t, backend)
+	sb.mu.Lock()
+	require.True(t, sb.applyAnkiCards(sb.beginAnkiLoad()()))
+	sb.mu.Unlock()
+	require.Equal(t, int64(1), sb.currentCardID())
+
+	// The next batch is prefetched once the queue runs low, and answers go
+	// on meanwhile with one request each.
+	release := backend.block()
+	for id := int64(1); id <= 5; id++ {
+		require.Equal(t, id, sb.currentCardID())
+		sb.RevealAnswer()
+		sb.AnswerCard(3)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, backend.answered)
+
+	// The queue ran out before the prefetch landed: loading.
+	assert.Zero(t, sb.currentCardID())
+	assert.Equal(t, StateQuestion, sb.AnkiState())
+
+	release()
+	assert.Eventually(t, func() bool { return sb.currentCardID() == 6 }, time.Second, 10*time.Millisecond)
+}
+
+func TestAnkiReloadDropsStalePrefetch(t *testing.T) {
+	backend := &fakeBackend{due: []int64{1, 2, 3, 4, 5, 6, 7}}
+	sb := newAnkiStatusBar(t, backend)
+	sb.mu.Lock()
+	require.True(t, sb.applyAnkiCards(sb.beginAnkiLoad()()))
+	sb.mu.Unlock()
+
+	// Start a prefetch of 6 and 7, then reload while it is in flight.
+	release := backend.block()
+	for range 3 {
+		sb.RevealAnswer()
+		sb.AnswerCard(3)
+	}
+	backend.mu.Lock()
+	backend.due = []int64{20}
+	backend.mu.Unlock()
+
+	sb.mu.Lock()
+	require.True(t, sb.prefetching)
+	sb.reloadAnkiCards()
+	sb.mu.Unlock()
+	release()
+
+	assert.Eventually(t, func() bool {
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		return !sb.prefetching && sb.currentCard != nil
+	}, time.Second, 10*time.Millisecond)
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	assert.Equal(t, int64(20), sb.currentCard.CardID)
+	assert.Empty(t, sb.cardQueue)
+	assert.Empty(t, sb.dueCards)
+	assert.Equal(t, 1, sb.dueCount)
+}
+
+// waitForSync waits until no sync is running and returns the number of syncs.
+func waitForSync(t *testing.T, sb *StatusBar, backend *fakeBackend) int {
+	assert.Eventually(t, func() bool {
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		return !sb.syncing
+	}, time.Second, 10*time.Millisecond)
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	return backend.syncs
+}
+
+func TestSyncAnkiIfDue(t *testing.T) {
+	backend := &fakeBackend{due: []int64{1}}
+	sb := newAnkiStatusBar(t, backend)
+	sb.syncedAt = time.Now().Add(-time.Hour)
+
+	// Syncing on a timer is off by default.
+	sb.SyncAnkiIfDue()
+	assert.Equal(t, 0, waitForSync(t, sb, backend))
+
+	sb.cfg.UserConfig.AnkiSyncInterval = 30
+	sb.SyncAnkiIfDue()
+	assert.Equal(t, 1, waitForSync(t, sb, backend))
+
+	// The interval starts again from the last sync.
+	sb.SyncAnkiIfDue()
+	assert.Equal(t, 1, waitForSync(t, sb, backend))
+
+	// Nothing is synced while Anki is unreachable.
+	sb.syncedAt = time.Now().Add(-time.Hour)
+	sb.ankiOffline = true
+	sb.SyncAnkiIfDue()
+	assert.Equal(t, 1, waitForSync(t, sb, backend))
+}
+
+func TestSyncAnkiAfterAnswers(t *testing.T) {
+	backend := &fakeBackend{due: []int64{1, 2, 3}}
+	sb := newAnkiStatusBar(t, backend)
+	sb.cfg.UserConfig.AnkiSyncAfter = 2
+	sb.mu.Lock()
+	require.True(t, sb.applyAnkiCards(sb.beginAnkiLoad()()))
+	sb.mu.Unlock()
+
+	sb.RevealAnswer()
+	sb.AnswerCard(3)
+	assert.Equal(t, 0, waitForSync(t, sb, backend))
+
+	sb.RevealAnswer()
+	sb.AnswerCard(3)
+	assert.Equal(t, 1, waitForSync(t, sb, backend))
+}
+
+func TestSyncAnkiShowsFailure(t *testing.T) {
+	backend := &fakeBackend{syncErr: errors.New("sync conflict")}
+	sb := newAnkiStatusBar(t, backend)
+	sb.cfg.UserConfig.AnkiSyncInterval = 30
+	sb.syncedAt = time.Now().Add(-time.Hour)
+	sb.mu.Lock()
+	require.True(t, sb.applyAnkiCards(sb.beginAnkiLoad()()))
+	sb.mu.Unlock()
+
+	sb.SyncAnkiIfDue()
+	waitForSync(t, sb, backend)
+	assert.Contains(t, sb.Status().Text, "sync failed")
+
+	// The failure shows whatever the state.
+	for _, state := range []AnkiState{StateDisconnected, StateNoDeck, StateUnauthorized} {
+		sb.mu.Lock()
+		sb.ankiState = state
+		sb.mu.Unlock()
+		require.NoError(t, sb.Redraw())
+		assert.Contains(t, sb.Status().Text, "sync failed", state.String())
+	}
+
+	// Anki closing in the meantime is not a failure.
+	sb.mu.Lock()
+	sb.ankiState = StateDone
+	sb.syncedAt = time.Now().Add(-time.Hour)
+	sb.mu.Unlock()
+	backend.mu.Lock()
+	backend.syncErr = anki.ErrorReadOnly
+	backend.mu.Unlock()
+
+	sb.SyncAnkiIfDue()
+	waitForSync(t, sb, backend)
+	assert.NotContains(t, sb.Status().Text, "sync failed")
+}
+
+func TestAnkiForbiddenIsNotUnauthorized(t *testing.T) {
+	sb := newAnkiStatusBar(t, &fakeBackend{})
+
+	sb.mu.Lock()
+	sb.setAnkiError(anki.ErrorForbidden)
+	sb.mu.Unlock()
+	require.NoError(t, sb.Redraw())
+
+	status := sb.Status()
+	assert.Equal(t, "forbidden", status.State)
+	assert.Contains(t, status.Text, "webCorsOriginList")
+	assert.NotContains(t, status.Text, "anki_api_key")
+}
+
+func TestOfflineCardsKeepTheirDeck(t *testing.T) {
+	sb := newAnkiStatusBar(t, &fakeBackend{})
+	sb.cacheAnkiCards(sb.source().Search(), []*anki.CardInfo{
+		{CardID: 1, DeckName: "Japanese::N5", Question: "雨", Answer: "rain"},
+		{CardID: 2, DeckName: "Japanese::N4", Question: "雪", Answer: "snow"},
+	})
+
+	sb.goOffline()
+	require.NotNil(t, sb.currentCard)
+	assert.Equal(t, "Japanese::N5", sb.currentCard.DeckName)
+	assert.Equal(t, "Japanese::N5", sb.ankiLineData(nil, nil).Deck)
+
+	// A skipped card comes back with its deck.
+	sb.Skip()
+	assert.Equal(t, "Japanese::N4", sb.currentCard.DeckName)
+	sb.Skip()
+	assert.Equal(t, int64(1), sb.currentCard.CardID)
+	assert.Equal(t, "Japanese::N5", sb.currentCard.DeckName)
+}
+
+func TestLevelCompletionIsCheckedAfterAnswers(t *testing.T) {
+	database := openTestDB(t)
+	require.NoError(t, database.SeedVocab([]data.Word{
+		{Word: "犬", Meaning: "dog", Furigana: "いぬ", Romaji: "inu", Level: 5},
+	}))
+	sb := &StatusBar{
+		cfg:  &config.Config{UserConfig: config.UserConfig{JLPTLevel: []int{5}}},
+		svc:  service.New(database),
+		sink: &writerSink{w: &bytes.Buffer{}},
+		mode: VocabMode,
+	}
+
+	require.NoError(t, sb.refresh())
+	require.NotNil(t, sb.currentWord)
+	assert.True(t, sb.completionChecked)
+
+	// The last word of the level is introduced by its answer.
+	sb.vocabState = StateAnswer
+	sb.answerLocal(3)
+	assert.Equal(t, "N5 complete", sb.notice)
+	assert.True(t, sb.completionChecked)
+
+	// Refreshes without an answer do not check again.
+	sb.notice = ""
+	require.NoError(t, sb.refresh())
+	assert.Empty(t, sb.notice)
+}